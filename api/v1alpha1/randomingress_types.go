@@ -9,6 +9,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -18,9 +19,259 @@ import (
 type RandomIngressSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
-	IngressTemplate IngressTemplateSpec `json:"ingressTemplate"`
+	// IngressTemplate is the template of the generated Ingresses.
+	// It must be left empty when another kind of object is generated instead, like with HTTPRouteTemplate,
+	// OpenShiftRouteTemplate, VirtualServiceTemplate or TraefikIngressRouteTemplate.
+	// +optional
+	IngressTemplate IngressTemplateSpec `json:"ingressTemplate,omitempty"`
+
+	// HTTPRouteTemplate, when set, generates Gateway API HTTPRoutes instead of Ingresses.
+	// Its spec is a gateway.networking.k8s.io/v1 HTTPRouteSpec, whose hostnames must carry placeholders.
+	// +optional
+	HTTPRouteTemplate *ObjectTemplateSpec `json:"httpRouteTemplate,omitempty"`
+
+	// OpenShiftRouteTemplate, when set, generates OpenShift Routes instead of Ingresses.
+	// Its spec is a route.openshift.io/v1 RouteSpec, whose host must carry placeholders.
+	// It cannot be set along with the templates of other routes.
+	// +optional
+	OpenShiftRouteTemplate *ObjectTemplateSpec `json:"openShiftRouteTemplate,omitempty"`
+
+	// VirtualServiceTemplate, when set, generates Istio VirtualServices instead of Ingresses.
+	// Its spec is a networking.istio.io/v1beta1 VirtualService spec, whose hosts must carry placeholders.
+	// It cannot be set along with the templates of other routes.
+	// +optional
+	VirtualServiceTemplate *VirtualServiceTemplateSpec `json:"virtualServiceTemplate,omitempty"`
+
+	// TraefikIngressRouteTemplate, when set, generates Traefik IngressRoutes instead of Ingresses.
+	// Its spec is a traefik.io/v1alpha1 IngressRoute spec, whose route matches must carry placeholders.
+	// It cannot be set along with the templates of other routes, nor with a readiness gate.
+	// +optional
+	TraefikIngressRouteTemplate *ObjectTemplateSpec `json:"traefikIngressRouteTemplate,omitempty"`
+
+	// MaxLifetime is the maximum duration of each Ingress generated from this RandomIngress.
+	// Defaults to the lifetime configured on the operator, and must lie within the bounds configured on the operator.
+	// +optional
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+
+	// HandoverDuration is the duration during which an old and a new Ingress coexist.
+	// The new Ingress is created that much time before the old one expires.
+	// Defaults to the handover duration configured on the operator, and must be shorter than MaxLifetime.
+	// +optional
+	HandoverDuration *metav1.Duration `json:"handoverDuration,omitempty"`
+
+	// RotationRequest asks for the generated Ingresses to be replaced now, ahead of their expiration.
+	// +optional
+	RotationRequest *RotationRequest `json:"rotationRequest,omitempty"`
+
+	// Suspend pauses the rotation: expired Ingresses are kept, and no new Ingress is created.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SuspendUntil is the time at which the rotation resumes automatically when suspended.
+	// Without it, the rotation stays suspended until Suspend is unset.
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
+
+	// RotationSchedule aligns the rotations on wall-clock times: each Ingress expires at a tick of the schedule,
+	// and its replacement is created the handover duration before that tick.
+	// The operator max lifetime does not apply to scheduled rotations, but MaxLifetime still caps them when set.
+	// +optional
+	RotationSchedule *RotationSchedule `json:"rotationSchedule,omitempty"`
+
+	// BlackoutWindows are periods during which rotations are postponed, in addition to the
+	// blackout windows configured on the operator. The current Ingress is kept alive until the end
+	// of the window, up to the maximum deferral configured on the operator.
+	// Rotation requests and template changes are applied regardless of blackout windows.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// ReadinessGate, when set, keeps expired Ingresses alive until the Ingress replacing them is served
+	// by the ingress controller, i.e. reports a load balancer address.
+	// Immediate rotation requests are applied regardless of the readiness gate.
+	// +optional
+	ReadinessGate *ReadinessGate `json:"readinessGate,omitempty"`
+
+	// Token defines the random part substituted to the |RANDOM| placeholder of hosts.
+	// Defaults to a random UUID.
+	// +optional
+	Token *TokenSpec `json:"token,omitempty"`
+
+	// Placeholders configures the named placeholders of hosts, like |RANDOM:admin|, each substituted by its own token.
+	// Named placeholders which are not listed here use Token and MaxLifetime.
+	// +optional
+	Placeholders []Placeholder `json:"placeholders,omitempty"`
 }
 
+// Placeholder configures the tokens of a named placeholder.
+type Placeholder struct {
+	// Name of the placeholder, as in |RANDOM:<name>|.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Token defines how the tokens of the placeholder are generated. Defaults to spec.token.
+	// +optional
+	Token *TokenSpec `json:"token,omitempty"`
+
+	// MaxLifetime is the maximum duration of the tokens of the placeholder. Defaults to spec.maxLifetime.
+	// When a token expires, the Ingress is replaced by one keeping the other tokens until they expire.
+	// +optional
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+}
+
+// TokenSpec defines how random tokens are generated.
+type TokenSpec struct {
+	// Format of the token. Defaults to uuidv4.
+	// +optional
+	Format TokenFormat `json:"format,omitempty"`
+
+	// EntropyBits is the minimum number of random bits of the token, 128 by default, or 64 for words tokens.
+	// It cannot be set for uuidv4 tokens, which always have 122 random bits.
+	// +optional
+	EntropyBits *int32 `json:"entropyBits,omitempty"`
+
+	// WordCount is the number of words of words tokens, each bringing almost 13 random bits.
+	// Defaults to the number of words needed to reach EntropyBits.
+	// +optional
+	WordCount *int32 `json:"wordCount,omitempty"`
+
+	// Separator joins the words of words tokens. Defaults to "-".
+	// +kubebuilder:validation:Enum="-";""
+	// +optional
+	Separator *string `json:"separator,omitempty"`
+
+	// DerivedFrom, when set, derives the tokens from a shared secret instead of drawing them at random,
+	// so that operators running in several clusters generate the same hosts at the same time.
+	// +optional
+	DerivedFrom *TokenDerivation `json:"derivedFrom,omitempty"`
+}
+
+// TokenDerivation defines how tokens are derived from a shared secret.
+// Each token is HMAC-SHA256(secret, identity, epoch index), where epochs are aligned on the max lifetime
+// since the Unix epoch: every Ingress expires at the end of the epoch it was generated for.
+type TokenDerivation struct {
+	// SecretKeyRef selects the key holding the HMAC secret, in a Secret of the namespace of the RandomIngress.
+	SecretKeyRef corev1.SecretKeySelector `json:"secretKeyRef"`
+
+	// Identity distinguishes the RandomIngresses sharing a secret.
+	// Defaults to the namespace and name of the RandomIngress.
+	// +optional
+	Identity string `json:"identity,omitempty"`
+}
+
+// TokenFormat is the encoding of a random token.
+// +kubebuilder:validation:Enum=uuidv4;hex;base32;base36;words
+type TokenFormat string
+
+const (
+	// TokenFormatUUIDv4 is a random UUID, e.g. 2bc112e6-c232-43ab-a658-2e29c21a6695.
+	TokenFormatUUIDv4 TokenFormat = "uuidv4"
+
+	// TokenFormatHex is a lowercase hexadecimal string.
+	TokenFormatHex TokenFormat = "hex"
+
+	// TokenFormatBase32 is a lowercase base32 string, using the RFC 4648 alphabet.
+	TokenFormatBase32 TokenFormat = "base32"
+
+	// TokenFormatBase36 is a lowercase alphanumeric string.
+	TokenFormatBase36 TokenFormat = "base36"
+
+	// TokenFormatWords is a sequence of English words, easy to read out loud, e.g. "crisp-ladder-ocean".
+	TokenFormatWords TokenFormat = "words"
+)
+
+// ReadinessGate defines when a new Ingress is considered served.
+type ReadinessGate struct {
+	// MatchAddresses also requires the new Ingress to report every load balancer address of the Ingress it replaces.
+	// +optional
+	MatchAddresses bool `json:"matchAddresses,omitempty"`
+
+	// MaxExtension bounds how long an expired Ingress is kept alive waiting for its replacement.
+	// Defaults to the maximum extension configured on the operator.
+	// +optional
+	MaxExtension *metav1.Duration `json:"maxExtension,omitempty"`
+}
+
+// BlackoutWindow is a period during which rotations are postponed.
+// It is either an absolute range, with Start and End, or a weekly recurring window.
+type BlackoutWindow struct {
+	// Name of the window, reported when it defers a rotation.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Start of an absolute window.
+	// +optional
+	Start *metav1.Time `json:"start,omitempty"`
+
+	// End of an absolute window.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+
+	// Weekly defines a window recurring every week.
+	// +optional
+	Weekly *WeeklyWindow `json:"weekly,omitempty"`
+}
+
+// WeeklyWindow is a window recurring every week.
+type WeeklyWindow struct {
+	// Days of the week on which the window starts.
+	// +kubebuilder:validation:MinItems=1
+	Days []Weekday `json:"days"`
+
+	// StartTime is the time of the day at which the window starts, in HH:MM format.
+	StartTime string `json:"startTime"`
+
+	// Duration of the window, at most a week.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the name of the time zone of StartTime, e.g. "Europe/Paris". Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Weekday is a day of the week.
+// +kubebuilder:validation:Enum=Sunday;Monday;Tuesday;Wednesday;Thursday;Friday;Saturday
+type Weekday string
+
+// RotationSchedule defines the wall-clock times at which generated Ingresses expire.
+type RotationSchedule struct {
+	// Cron is the schedule in Cron format, e.g. "0 3 * * *" for every day at 03:00.
+	Cron string `json:"cron"`
+
+	// TimeZone is the name of the time zone in which the schedule is interpreted, e.g. "Europe/Paris".
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// RotationRequest asks for an immediate rotation of the generated Ingresses, e.g. when a random host has leaked.
+type RotationRequest struct {
+	// Token identifies the request: every change of its value triggers a new rotation.
+	Token string `json:"token"`
+
+	// RequestedBy is a free-form note on who asked for the rotation. It is not verified: anyone allowed to edit
+	// the RandomIngress can set it to any value. The Kubernetes audit log tells who actually changed the request.
+	// +optional
+	RequestedBy string `json:"requestedBy,omitempty"`
+
+	// Strategy tells how the current Ingresses are replaced.
+	// Handover, the default, keeps them alive for the handover duration after the new Ingress is created.
+	// Immediate deletes them right away.
+	// +optional
+	Strategy RotationStrategy `json:"strategy,omitempty"`
+}
+
+// RotationStrategy tells how the current Ingresses are replaced on a rotation request.
+// +kubebuilder:validation:Enum=Handover;Immediate
+type RotationStrategy string
+
+const (
+	// RotationStrategyHandover keeps the current Ingresses alive for the handover duration.
+	RotationStrategyHandover RotationStrategy = "Handover"
+
+	// RotationStrategyImmediate deletes the current Ingresses right away.
+	RotationStrategyImmediate RotationStrategy = "Immediate"
+)
+
 // IngressTemplate defines the template that should be used to instantiate the Ingress resource.
 type IngressTemplateSpec struct {
 	// Metadata to add to the ingresses created from this template.
@@ -32,6 +283,40 @@ type IngressTemplateSpec struct {
 	Spec networkingv1.IngressSpec `json:"spec,omitempty"`
 }
 
+// ObjectTemplateSpec defines the template of objects of a kind whose API the operator does not depend on,
+// like Gateway API HTTPRoutes, OpenShift Routes or Traefik IngressRoutes. Its spec is copied as is, apart from the substitution of the placeholders.
+type ObjectTemplateSpec struct {
+	// Metadata to add to the objects created from this template.
+	// +optional
+	Metadata IngressTemplateMetadata `json:"metadata,omitempty"`
+
+	// Specification of the objects to instantiate.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	Spec runtime.RawExtension `json:"spec"`
+}
+
+// VirtualServiceTemplateSpec defines the template of Istio VirtualServices.
+type VirtualServiceTemplateSpec struct {
+	ObjectTemplateSpec `json:",inline"`
+
+	// SyncGateway, when set, keeps the hosts of an Istio Gateway in sync with the hosts of the live VirtualServices.
+	// +optional
+	SyncGateway *GatewaySync `json:"syncGateway,omitempty"`
+}
+
+// GatewaySync designates the servers of an Istio Gateway whose hosts follow the generated VirtualServices.
+type GatewaySync struct {
+	// Name of the Gateway, in the namespace of the RandomIngress.
+	// The VirtualServices must be bound to it through their gateways.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Servers lists the names of the servers of the Gateway whose hosts are kept in sync. Defaults to every server.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+}
+
 // IngressTemplateMetadata defines the metadata that should be added to the instantiated Ingress resources.
 // It only contains vetted fields of metadata: the other usual fields are managed by the RandomIngress operator.
 type IngressTemplateMetadata struct {
@@ -62,8 +347,150 @@ type RandomIngressStatus struct {
 	// and create a new one with a new random part.
 	// +optional
 	NextRenewalTime *metav1.Time `json:"nextRenewalTime,omitempty"`
+
+	// CurrentHosts are the hosts of the most recently generated Ingress.
+	// +optional
+	CurrentHosts []string `json:"currentHosts,omitempty"`
+
+	// ActiveIngresses lists the Ingresses currently generated from this RandomIngress, newest first.
+	// +optional
+	ActiveIngresses []ActiveIngress `json:"activeIngresses,omitempty"`
+
+	// LastRotationRequest records the last rotation request observed by the controller.
+	// +optional
+	LastRotationRequest *RotationRequestStatus `json:"lastRotationRequest,omitempty"`
+
+	// TokenEntropyBits is the number of random bits of the tokens generated from the current spec,
+	// the lowest one among the placeholders.
+	// +optional
+	TokenEntropyBits int32 `json:"tokenEntropyBits,omitempty"`
+
+	// Tokens are the current values of the named placeholders, from the most recently generated Ingress.
+	// +optional
+	Tokens []TokenStatus `json:"tokens,omitempty"`
+
+	// LastIngressDeletion records the last deletion of an expired Ingress, and how late it happened.
+	// +optional
+	LastIngressDeletion *IngressDeletion `json:"lastIngressDeletion,omitempty"`
+
+	// TokenHistory records the tokens issued for this RandomIngress, so that none is issued again once expired.
+	// +optional
+	TokenHistory *TokenHistory `json:"tokenHistory,omitempty"`
+
+	// GatewayHosts records the hosts added to an Istio Gateway to follow the generated VirtualServices,
+	// to remove them once they are no longer served.
+	// +optional
+	GatewayHosts *GatewayHosts `json:"gatewayHosts,omitempty"`
+}
+
+// GatewayHosts are the hosts the operator added to the servers of an Istio Gateway.
+type GatewayHosts struct {
+	// Name of the Gateway, in the namespace of the RandomIngress.
+	Name string `json:"name"`
+
+	// Hosts added to the servers of the Gateway.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
 }
 
+// TokenHistory records salted hashes of the most recently issued tokens, never their values.
+type TokenHistory struct {
+	// Salt mixed with the tokens before hashing them, hex-encoded.
+	Salt string `json:"salt"`
+
+	// Entries are the hashes of the issued tokens, oldest first.
+	// +optional
+	Entries []TokenHistoryEntry `json:"entries,omitempty"`
+}
+
+// TokenHistoryEntry is an issued token.
+type TokenHistoryEntry struct {
+	// Hash of the salted token, hex-encoded.
+	Hash string `json:"hash"`
+
+	// ExpiresAt is the time at which the token expires, after which it must not be issued again.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// TokenStatus is the current value of a named placeholder.
+type TokenStatus struct {
+	// Name of the placeholder.
+	Name string `json:"name"`
+
+	// Value substituted to the placeholder.
+	Value string `json:"value"`
+
+	// ExpiresAt is the time at which the value expires.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// IngressDeletion records the deletion of an expired Ingress.
+type IngressDeletion struct {
+	// Name of the deleted Ingress.
+	Name string `json:"name"`
+
+	// ExpiresAt is the time at which the Ingress was due to be deleted: its expiration, or with a readiness gate,
+	// the end of its maximum extension.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+
+	// DeletedAt is the time at which the Ingress was actually deleted.
+	DeletedAt metav1.Time `json:"deletedAt"`
+
+	// Delay is how late the Ingress was deleted after its expiration.
+	Delay metav1.Duration `json:"delay"`
+}
+
+// RotationRequestStatus records a rotation request observed by the controller.
+type RotationRequestStatus struct {
+	// Token of the request.
+	Token string `json:"token"`
+
+	// RequestedBy is the unverified note of the request on who asked for the rotation.
+	// +optional
+	RequestedBy string `json:"requestedBy,omitempty"`
+
+	// Strategy used to replace the Ingresses.
+	// +optional
+	Strategy RotationStrategy `json:"strategy,omitempty"`
+
+	// RequestedAt is the time at which the controller observed the request.
+	RequestedAt metav1.Time `json:"requestedAt"`
+}
+
+// ActiveIngress describes an Ingress generated from a RandomIngress.
+type ActiveIngress struct {
+	// Name of the Ingress.
+	Name string `json:"name"`
+
+	// Hosts of the Ingress rules.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+
+	// CreatedAt is the time at which the Ingress was issued.
+	CreatedAt metav1.Time `json:"createdAt"`
+
+	// ExpiresAt is the time at which the Ingress will be deleted.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+
+	// Phase of the Ingress in the rotation.
+	Phase ActiveIngressPhase `json:"phase"`
+}
+
+// ActiveIngressPhase describes the role of a generated Ingress in the rotation.
+// +kubebuilder:validation:Enum=Active;Handover;Expiring
+type ActiveIngressPhase string
+
+const (
+	// ActiveIngressActive means the Ingress is the only one serving its RandomIngress.
+	ActiveIngressActive ActiveIngressPhase = "Active"
+
+	// ActiveIngressHandover means the Ingress is the newest one, and older Ingresses are still live.
+	ActiveIngressHandover ActiveIngressPhase = "Handover"
+
+	// ActiveIngressExpiring means the Ingress is being replaced, and will be deleted at its expiration time.
+	ActiveIngressExpiring ActiveIngressPhase = "Expiring"
+)
+
 // RandomIngressCondition represents an observation on the current state of the randomingress.
 type RandomIngressCondition struct {
 	// Type of deployment condition.
@@ -81,19 +508,44 @@ type RandomIngressCondition struct {
 }
 
 // RandomIngressConditionType enumerates the possible conditions of a randomingress.
-// +kubebuilder:validation:Enum=Valid;Progressing
+// +kubebuilder:validation:Enum=Valid;Progressing;Suspended;RotationDeferred;ReplacementFailed;PerHostCertificateRequired;HostConflict;NewIngressNotReady
 type RandomIngressConditionType string
 
 const (
 	// Valid means the randomingress spec passes validation.
 	RandomIngressValid RandomIngressConditionType = "Valid"
 
-	// Progressing means the randomingress is currently changing the managed ingress.
+	// Progressing means the randomingress is currently changing the managed ingress:
+	// several generations of Ingress overlap until the handover is over.
 	RandomIngressProgressing RandomIngressConditionType = "Progressing"
+
+	// Suspended means the rotation of the randomingress is paused.
+	RandomIngressSuspended RandomIngressConditionType = "Suspended"
+
+	// RotationDeferred means the next rotation of the randomingress is postponed by a blackout window.
+	RandomIngressRotationDeferred RandomIngressConditionType = "RotationDeferred"
+
+	// ReplacementFailed means the randomingress could not create the Ingress replacing the expired ones:
+	// the last serving Ingress is kept until a replacement is created.
+	RandomIngressReplacementFailed RandomIngressConditionType = "ReplacementFailed"
+
+	// PerHostCertificateRequired means the TLS section of the template lists random hosts which no wildcard covers:
+	// a certificate must be issued for the hosts of every generated Ingress, e.g. by cert-manager.
+	RandomIngressPerHostCertificateRequired RandomIngressConditionType = "PerHostCertificateRequired"
+
+	// HostConflict means generated hosts or paths of the randomingress are also served by Ingresses it does not control,
+	// and could not be made unique by regenerating their tokens. Ingresses sharing its static hosts are only reported.
+	RandomIngressHostConflict RandomIngressConditionType = "HostConflict"
+
+	// NewIngressNotReady means expired Ingresses are kept by the readiness gate until their replacement is served.
+	RandomIngressNewIngressNotReady RandomIngressConditionType = "NewIngressNotReady"
 )
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.status.currentHosts[0]`
+//+kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.nextRenewalTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RandomIngress is the Schema for the randomingresses API
 type RandomIngress struct {
@@ -11,9 +11,118 @@ SPDX-License-Identifier: Apache-2.0
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveIngress) DeepCopyInto(out *ActiveIngress) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveIngress.
+func (in *ActiveIngress) DeepCopy() *ActiveIngress {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveIngress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutWindow) DeepCopyInto(out *BlackoutWindow) {
+	*out = *in
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	if in.Weekly != nil {
+		in, out := &in.Weekly, &out.Weekly
+		*out = new(WeeklyWindow)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutWindow.
+func (in *BlackoutWindow) DeepCopy() *BlackoutWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewayHosts) DeepCopyInto(out *GatewayHosts) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayHosts.
+func (in *GatewayHosts) DeepCopy() *GatewayHosts {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayHosts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewaySync) DeepCopyInto(out *GatewaySync) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewaySync.
+func (in *GatewaySync) DeepCopy() *GatewaySync {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewaySync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDeletion) DeepCopyInto(out *IngressDeletion) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+	in.DeletedAt.DeepCopyInto(&out.DeletedAt)
+	out.Delay = in.Delay
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDeletion.
+func (in *IngressDeletion) DeepCopy() *IngressDeletion {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDeletion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressTemplateMetadata) DeepCopyInto(out *IngressTemplateMetadata) {
 	*out = *in
@@ -60,6 +169,48 @@ func (in *IngressTemplateSpec) DeepCopy() *IngressTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectTemplateSpec) DeepCopyInto(out *ObjectTemplateSpec) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectTemplateSpec.
+func (in *ObjectTemplateSpec) DeepCopy() *ObjectTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Placeholder) DeepCopyInto(out *Placeholder) {
+	*out = *in
+	if in.Token != nil {
+		in, out := &in.Token, &out.Token
+		*out = new(TokenSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxLifetime != nil {
+		in, out := &in.MaxLifetime, &out.MaxLifetime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Placeholder.
+func (in *Placeholder) DeepCopy() *Placeholder {
+	if in == nil {
+		return nil
+	}
+	out := new(Placeholder)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RandomIngress) DeepCopyInto(out *RandomIngress) {
 	*out = *in
@@ -140,6 +291,74 @@ func (in *RandomIngressList) DeepCopyObject() runtime.Object {
 func (in *RandomIngressSpec) DeepCopyInto(out *RandomIngressSpec) {
 	*out = *in
 	in.IngressTemplate.DeepCopyInto(&out.IngressTemplate)
+	if in.HTTPRouteTemplate != nil {
+		in, out := &in.HTTPRouteTemplate, &out.HTTPRouteTemplate
+		*out = new(ObjectTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OpenShiftRouteTemplate != nil {
+		in, out := &in.OpenShiftRouteTemplate, &out.OpenShiftRouteTemplate
+		*out = new(ObjectTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VirtualServiceTemplate != nil {
+		in, out := &in.VirtualServiceTemplate, &out.VirtualServiceTemplate
+		*out = new(VirtualServiceTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TraefikIngressRouteTemplate != nil {
+		in, out := &in.TraefikIngressRouteTemplate, &out.TraefikIngressRouteTemplate
+		*out = new(ObjectTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxLifetime != nil {
+		in, out := &in.MaxLifetime, &out.MaxLifetime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.HandoverDuration != nil {
+		in, out := &in.HandoverDuration, &out.HandoverDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RotationRequest != nil {
+		in, out := &in.RotationRequest, &out.RotationRequest
+		*out = new(RotationRequest)
+		**out = **in
+	}
+	if in.SuspendUntil != nil {
+		in, out := &in.SuspendUntil, &out.SuspendUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.RotationSchedule != nil {
+		in, out := &in.RotationSchedule, &out.RotationSchedule
+		*out = new(RotationSchedule)
+		**out = **in
+	}
+	if in.BlackoutWindows != nil {
+		in, out := &in.BlackoutWindows, &out.BlackoutWindows
+		*out = make([]BlackoutWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadinessGate != nil {
+		in, out := &in.ReadinessGate, &out.ReadinessGate
+		*out = new(ReadinessGate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Token != nil {
+		in, out := &in.Token, &out.Token
+		*out = new(TokenSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placeholders != nil {
+		in, out := &in.Placeholders, &out.Placeholders
+		*out = make([]Placeholder, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RandomIngressSpec.
@@ -166,6 +385,45 @@ func (in *RandomIngressStatus) DeepCopyInto(out *RandomIngressStatus) {
 		in, out := &in.NextRenewalTime, &out.NextRenewalTime
 		*out = (*in).DeepCopy()
 	}
+	if in.CurrentHosts != nil {
+		in, out := &in.CurrentHosts, &out.CurrentHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ActiveIngresses != nil {
+		in, out := &in.ActiveIngresses, &out.ActiveIngresses
+		*out = make([]ActiveIngress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRotationRequest != nil {
+		in, out := &in.LastRotationRequest, &out.LastRotationRequest
+		*out = new(RotationRequestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tokens != nil {
+		in, out := &in.Tokens, &out.Tokens
+		*out = make([]TokenStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastIngressDeletion != nil {
+		in, out := &in.LastIngressDeletion, &out.LastIngressDeletion
+		*out = new(IngressDeletion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenHistory != nil {
+		in, out := &in.TokenHistory, &out.TokenHistory
+		*out = new(TokenHistory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GatewayHosts != nil {
+		in, out := &in.GatewayHosts, &out.GatewayHosts
+		*out = new(GatewayHosts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RandomIngressStatus.
@@ -177,3 +435,216 @@ func (in *RandomIngressStatus) DeepCopy() *RandomIngressStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessGate) DeepCopyInto(out *ReadinessGate) {
+	*out = *in
+	if in.MaxExtension != nil {
+		in, out := &in.MaxExtension, &out.MaxExtension
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessGate.
+func (in *ReadinessGate) DeepCopy() *ReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationRequest) DeepCopyInto(out *RotationRequest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationRequest.
+func (in *RotationRequest) DeepCopy() *RotationRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationRequestStatus) DeepCopyInto(out *RotationRequestStatus) {
+	*out = *in
+	in.RequestedAt.DeepCopyInto(&out.RequestedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationRequestStatus.
+func (in *RotationRequestStatus) DeepCopy() *RotationRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationSchedule) DeepCopyInto(out *RotationSchedule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationSchedule.
+func (in *RotationSchedule) DeepCopy() *RotationSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenDerivation) DeepCopyInto(out *TokenDerivation) {
+	*out = *in
+	in.SecretKeyRef.DeepCopyInto(&out.SecretKeyRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenDerivation.
+func (in *TokenDerivation) DeepCopy() *TokenDerivation {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenDerivation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenHistory) DeepCopyInto(out *TokenHistory) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]TokenHistoryEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenHistory.
+func (in *TokenHistory) DeepCopy() *TokenHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenHistoryEntry) DeepCopyInto(out *TokenHistoryEntry) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenHistoryEntry.
+func (in *TokenHistoryEntry) DeepCopy() *TokenHistoryEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenHistoryEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenSpec) DeepCopyInto(out *TokenSpec) {
+	*out = *in
+	if in.EntropyBits != nil {
+		in, out := &in.EntropyBits, &out.EntropyBits
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WordCount != nil {
+		in, out := &in.WordCount, &out.WordCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Separator != nil {
+		in, out := &in.Separator, &out.Separator
+		*out = new(string)
+		**out = **in
+	}
+	if in.DerivedFrom != nil {
+		in, out := &in.DerivedFrom, &out.DerivedFrom
+		*out = new(TokenDerivation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenSpec.
+func (in *TokenSpec) DeepCopy() *TokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenStatus) DeepCopyInto(out *TokenStatus) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenStatus.
+func (in *TokenStatus) DeepCopy() *TokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualServiceTemplateSpec) DeepCopyInto(out *VirtualServiceTemplateSpec) {
+	*out = *in
+	in.ObjectTemplateSpec.DeepCopyInto(&out.ObjectTemplateSpec)
+	if in.SyncGateway != nil {
+		in, out := &in.SyncGateway, &out.SyncGateway
+		*out = new(GatewaySync)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualServiceTemplateSpec.
+func (in *VirtualServiceTemplateSpec) DeepCopy() *VirtualServiceTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualServiceTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WeeklyWindow) DeepCopyInto(out *WeeklyWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WeeklyWindow.
+func (in *WeeklyWindow) DeepCopy() *WeeklyWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(WeeklyWindow)
+	in.DeepCopyInto(out)
+	return out
+}
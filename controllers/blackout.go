@@ -0,0 +1,197 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+const (
+	weeklyWindowTimeFormat = "15:04"
+	week                   = 7 * 24 * time.Hour
+
+	// maxChainedBlackoutWindows bounds the number of consecutive windows a rotation can be deferred by,
+	// in case the windows leave no room for a rotation at all.
+	maxChainedBlackoutWindows = 100
+)
+
+// blackoutPeriod is an occurrence of a blackout window.
+type blackoutPeriod struct {
+	name  string
+	start time.Time
+	end   time.Time
+}
+
+// LoadBlackoutWindows reads the operator-wide blackout windows from a YAML file containing a list of windows.
+func LoadBlackoutWindows(path string) ([]networkingv1alpha1.BlackoutWindow, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []networkingv1alpha1.BlackoutWindow
+	if err := yaml.UnmarshalStrict(content, &windows); err != nil {
+		return nil, fmt.Errorf("failed to parse blackout windows: %w", err)
+	}
+
+	if errs := validateBlackoutWindows(windows, field.NewPath("blackoutWindows")); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	return windows, nil
+}
+
+func validateBlackoutWindows(windows []networkingv1alpha1.BlackoutWindow, windowsPath *field.Path) (errs field.ErrorList) {
+	for i, window := range windows {
+		windowPath := windowsPath.Index(i)
+
+		switch {
+		case window.Weekly != nil && (window.Start != nil || window.End != nil):
+			errs = append(errs, field.Invalid(windowPath, window.Name, "must be either an absolute or a weekly window"))
+		case window.Weekly != nil:
+			errs = append(errs, validateWeeklyWindow(window.Weekly, windowPath.Child("weekly"))...)
+		case window.Start == nil || window.End == nil:
+			errs = append(errs, field.Required(windowPath, "start and end, or weekly, must be set"))
+		case !window.Start.Before(window.End):
+			errs = append(errs, field.Invalid(windowPath.Child("end"), window.End.UTC().Format(time.RFC3339), "must be after start"))
+		}
+	}
+
+	return errs
+}
+
+func validateWeeklyWindow(weekly *networkingv1alpha1.WeeklyWindow, weeklyPath *field.Path) (errs field.ErrorList) {
+	if len(weekly.Days) == 0 {
+		errs = append(errs, field.Required(weeklyPath.Child("days"), "at least one day must be set"))
+	}
+
+	for i, day := range weekly.Days {
+		if _, ok := parseWeekday(day); !ok {
+			errs = append(errs, field.NotSupported(weeklyPath.Child("days").Index(i), day, weekdayNames()))
+		}
+	}
+
+	if _, err := time.Parse(weeklyWindowTimeFormat, weekly.StartTime); err != nil {
+		errs = append(errs, field.Invalid(weeklyPath.Child("startTime"), weekly.StartTime, "must be in HH:MM format"))
+	}
+
+	if weekly.Duration.Duration <= 0 || weekly.Duration.Duration > week {
+		errs = append(errs, field.Invalid(weeklyPath.Child("duration"), weekly.Duration.Duration.String(), "must be positive and at most a week"))
+	}
+
+	if _, err := time.LoadLocation(weekly.TimeZone); err != nil {
+		errs = append(errs, field.Invalid(weeklyPath.Child("timeZone"), weekly.TimeZone, "unknown time zone"))
+	}
+
+	return errs
+}
+
+// blackoutPeriods returns the occurrences of the windows that overlap the given range.
+// Invalid windows are ignored, they are reported by the validation.
+func blackoutPeriods(windows []networkingv1alpha1.BlackoutWindow, from, to time.Time) []blackoutPeriod {
+	var periods []blackoutPeriod
+
+	for _, window := range windows {
+		if window.Weekly == nil {
+			if window.Start != nil && window.End != nil && overlaps(window.Start.Time, window.End.Time, from, to) {
+				periods = append(periods, blackoutPeriod{name: window.Name, start: window.Start.Time, end: window.End.Time})
+			}
+			continue
+		}
+
+		location, err := time.LoadLocation(window.Weekly.TimeZone)
+		if err != nil {
+			continue
+		}
+
+		startTime, err := time.Parse(weeklyWindowTimeFormat, window.Weekly.StartTime)
+		if err != nil {
+			continue
+		}
+
+		// Windows last at most a week, so occurrences starting a week before the range may still overlap it.
+		firstDay := from.In(location).AddDate(0, 0, -7)
+		for day := time.Date(firstDay.Year(), firstDay.Month(), firstDay.Day(), 0, 0, 0, 0, location); !day.After(to); day = day.AddDate(0, 0, 1) {
+			if !windowStartsOn(window.Weekly, day.Weekday()) {
+				continue
+			}
+
+			start := time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, location)
+			end := start.Add(window.Weekly.Duration.Duration)
+			if overlaps(start, end, from, to) {
+				periods = append(periods, blackoutPeriod{name: window.Name, start: start, end: end})
+			}
+		}
+	}
+
+	return periods
+}
+
+// deferRotation postpones a rotation made of a handover starting at handoverStart and lasting handoverDuration,
+// until it overlaps no blackout window. It returns the postponed handover start, and the last window that deferred it.
+func deferRotation(windows []networkingv1alpha1.BlackoutWindow, handoverStart time.Time, handoverDuration time.Duration) (time.Time, *blackoutPeriod) {
+	var deferredBy *blackoutPeriod
+
+	for i := 0; i < maxChainedBlackoutWindows; i++ {
+		periods := blackoutPeriods(windows, handoverStart, handoverStart.Add(handoverDuration))
+		if len(periods) == 0 {
+			break
+		}
+
+		latest := periods[0]
+		for _, period := range periods[1:] {
+			if period.end.After(latest.end) {
+				latest = period
+			}
+		}
+
+		handoverStart = latest.end
+		deferredBy = &latest
+	}
+
+	return handoverStart, deferredBy
+}
+
+// overlaps returns true if the period [start, end) overlaps the range [from, to].
+func overlaps(start, end, from, to time.Time) bool {
+	return !start.After(to) && from.Before(end)
+}
+
+func windowStartsOn(weekly *networkingv1alpha1.WeeklyWindow, weekday time.Weekday) bool {
+	for _, day := range weekly.Days {
+		if parsed, ok := parseWeekday(day); ok && parsed == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseWeekday(day networkingv1alpha1.Weekday) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if string(day) == weekday.String() {
+			return weekday, true
+		}
+	}
+
+	return 0, false
+}
+
+func weekdayNames() []string {
+	var names []string
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		names = append(names, weekday.String())
+	}
+
+	return names
+}
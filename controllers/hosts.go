@@ -0,0 +1,300 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+const ingressHostKey = ".spec.rules.host"
+
+const (
+	hostsInUseReason               = "HostsInUse"
+	staticHostsSharedReason        = "StaticHostsShared"
+	noHostConflictReason           = "NoHostConflict"
+	hostConflictDetectionOffReason = "DetectionDisabled"
+)
+
+// maxHostConflictAttempts is the number of times the tokens of a new Ingress are generated
+// before giving up on finding hosts that no other Ingress serves.
+const maxHostConflictAttempts = 3
+
+// hostConflicts maps hosts to the Ingresses, as namespace/name, that serve them along with a RandomIngress.
+type hostConflicts map[string][]string
+
+// validateHost checks that the hosts generated from the given rule host are valid DNS names,
+// by simulating the substitution of its placeholders with the longest tokens of their format.
+// Placeholders never contain dots, so the labels of the template host are the labels of the generated hosts.
+func validateHost(spec *networkingv1alpha1.RandomIngressSpec, host string, hostPath *field.Path) (errs field.ErrorList) {
+	substituted := substituteLongestTokens(spec, host)
+
+	domain := strings.TrimPrefix(substituted, "*.")
+	if strings.Contains(domain, "*") {
+		return append(errs, field.Invalid(hostPath, host, "a wildcard must be the whole first label of the host, as in *.example.com"))
+	}
+
+	labels := strings.Split(host, ".")
+	for i, label := range strings.Split(substituted, ".") {
+		if len(label) > validation.DNS1123LabelMaxLength {
+			errs = append(errs, field.Invalid(hostPath, host,
+				fmt.Sprintf("label %q must be no more than %d characters once substituted, got up to %d",
+					labels[i], validation.DNS1123LabelMaxLength, len(label))))
+		}
+	}
+
+	for _, msg := range validation.IsDNS1123Subdomain(domain) {
+		errs = append(errs, field.Invalid(hostPath, host, msg))
+	}
+
+	return errs
+}
+
+// validateHosts checks the DNS correctness of the hosts generated from the rules of the template,
+// and that no host is repeated across rules. Hosts already reported as invalid are skipped.
+func validateHosts(spec *networkingv1alpha1.RandomIngressSpec, rulesPath *field.Path, reported field.ErrorList) field.ErrorList {
+	hosts := make([]string, 0, len(spec.IngressTemplate.Spec.Rules))
+	for _, rule := range spec.IngressTemplate.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+
+	return validateHostList(spec, hosts, func(i int) *field.Path { return rulesPath.Index(i).Child("host") }, reported)
+}
+
+// validateHostList checks the DNS correctness of the hosts generated from the given template hosts, found at hostPath(i),
+// and that no host is repeated. Empty hosts and hosts already reported as invalid are skipped.
+func validateHostList(spec *networkingv1alpha1.RandomIngressSpec, hosts []string, hostPath func(int) *field.Path, reported field.ErrorList) (errs field.ErrorList) {
+	invalidHosts := map[string]bool{}
+	for _, err := range reported {
+		invalidHosts[err.Field] = true
+	}
+
+	seen := map[string]bool{}
+	for i, host := range hosts {
+		if host == "" || invalidHosts[hostPath(i).String()] {
+			continue
+		}
+
+		if seen[host] {
+			errs = append(errs, field.Duplicate(hostPath(i), host))
+			continue
+		}
+		seen[host] = true
+
+		errs = append(errs, validateHost(spec, host, hostPath(i))...)
+	}
+
+	return errs
+}
+
+// hostPath is a generated host to look up among the objects the RandomIngress does not control, or a generated path
+// served under a static host of the template: the random paths keep such hosts apart from the other Ingresses serving them.
+type hostPath struct {
+	host string
+	path string
+}
+
+func (h hostPath) String() string {
+	return h.host + h.path
+}
+
+// staticHosts returns the hosts of the template which have no placeholder.
+func staticHosts(spec *networkingv1alpha1.RandomIngressSpec) []hostPath {
+	var hosts []hostPath
+	for _, host := range specTarget(spec).templateHosts(spec) {
+		if host != "" && !placeholderPattern.MatchString(host) {
+			hosts = append(hosts, hostPath{host: host})
+		}
+	}
+
+	return hosts
+}
+
+// generatedHosts returns the hosts of the object generated from hosts of the template which have a placeholder.
+func generatedHosts(spec *networkingv1alpha1.RandomIngressSpec, obj client.Object) []string {
+	t := specTarget(spec)
+	objectHosts := t.hosts(obj)
+
+	var hosts []string
+	for i, host := range t.templateHosts(spec) {
+		if placeholderPattern.MatchString(host) && i < len(objectHosts) {
+			hosts = append(hosts, objectHosts[i])
+		}
+	}
+
+	return hosts
+}
+
+// generatedHostPaths returns the generated hosts of the object, and the paths of the Ingress generated from
+// the paths of the template which have a placeholder under a static host.
+func generatedHostPaths(spec *networkingv1alpha1.RandomIngressSpec, obj client.Object) []hostPath {
+	var hostPaths []hostPath
+	for _, host := range generatedHosts(spec, obj) {
+		hostPaths = append(hostPaths, hostPath{host: host})
+	}
+
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return hostPaths
+	}
+
+	for i, rule := range spec.IngressTemplate.Spec.Rules {
+		if rule.Host == "" || placeholderPattern.MatchString(rule.Host) || rule.HTTP == nil ||
+			i >= len(ingress.Spec.Rules) || ingress.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+
+		paths := ingress.Spec.Rules[i].HTTP.Paths
+		for j, path := range rule.HTTP.Paths {
+			if placeholderPattern.MatchString(path.Path) && j < len(paths) {
+				hostPaths = append(hostPaths, hostPath{host: rule.Host, path: paths[j].Path})
+			}
+		}
+	}
+
+	return hostPaths
+}
+
+// servesHostPath returns true if the object serves the given host, and the given path if any.
+// Random paths are only generated in Ingresses: objects of other kinds never serve them.
+func servesHostPath(obj client.Object, hp hostPath) bool {
+	if hp.path == "" {
+		return true
+	}
+
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return false
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != hp.host || rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			if path.Path == hp.path {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// findHostConflicts looks up the given hosts in the host indexes of the enabled kinds, across all namespaces,
+// and returns the ones served by objects the RandomIngress does not control, at the given path if any.
+func (r *RandomIngressReconciler) findHostConflicts(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, hostPaths []hostPath) (hostConflicts, error) {
+	conflicts := hostConflicts{}
+	checked := map[hostPath]bool{}
+	for _, hp := range hostPaths {
+		if checked[hp] {
+			continue
+		}
+		checked[hp] = true
+
+		for _, t := range r.targets() {
+			objects, err := t.list(ctx, r.Client, client.MatchingFields{ingressHostKey: hp.host})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %ss serving host %s: %w", t.groupVersionKind().Kind, hp.host, err)
+			}
+
+			for _, obj := range objects {
+				if owner := metav1.GetControllerOf(obj); owner != nil && owner.UID == randomIngress.UID {
+					continue
+				}
+
+				if servesHostPath(obj, hp) {
+					conflicts[hp.String()] = append(conflicts[hp.String()], obj.GetNamespace()+"/"+obj.GetName())
+				}
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// newIngress generates the Ingress replacing the current ones. When host conflict detection is enabled,
+// its tokens are generated again as long as its generated hosts or paths are served by other Ingresses.
+// The conflicts of the last attempt are returned along with the error if none succeeds.
+func (r *RandomIngressReconciler) newIngress(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, specHash string, settings rotationSettings, previous client.Object) (client.Object, hostConflicts, error) {
+	var conflicts hostConflicts
+	var lastName string
+	for attempt := 0; attempt < maxHostConflictAttempts; attempt++ {
+		ingress, err := r.createIngress(ctx, randomIngress, specHash, settings, previous)
+		if err != nil || !r.DetectHostConflicts {
+			return ingress, nil, err
+		}
+
+		// The name of the Ingress carries the hash of its tokens: the same name means that derived
+		// or previous tokens were generated again, trying more would give the same conflicts.
+		if ingress.GetName() == lastName {
+			break
+		}
+		lastName = ingress.GetName()
+
+		conflicts, err = r.findHostConflicts(ctx, randomIngress, generatedHostPaths(&randomIngress.Spec, ingress))
+		if err != nil {
+			return nil, nil, err
+		} else if len(conflicts) == 0 {
+			return ingress, nil, nil
+		}
+	}
+
+	return nil, conflicts, fmt.Errorf("generated hosts are already in use: %s", conflicts)
+}
+
+// String lists the conflicting hosts in order, along with the Ingresses serving them.
+func (c hostConflicts) String() string {
+	hosts := make([]string, 0, len(c))
+	for host := range c {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	descriptions := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		ingresses := append([]string(nil), c[host]...)
+		sort.Strings(ingresses)
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", host, strings.Join(ingresses, ", ")))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// hostConflictCondition reports the generated hosts and paths which are served by Ingresses the RandomIngress does
+// not control. Other Ingresses serving the static hosts of the template are only reported when there is no such conflict:
+// the random paths under these hosts keep the generated Ingresses apart from them.
+func (r *RandomIngressReconciler) hostConflictCondition(staticConflicts, conflicts hostConflicts) networkingv1alpha1.RandomIngressCondition {
+	if !r.DetectHostConflicts {
+		return r.newCondition(networkingv1alpha1.RandomIngressHostConflict, corev1.ConditionFalse, hostConflictDetectionOffReason,
+			"host conflict detection is disabled")
+	}
+
+	if len(conflicts) == 0 && len(staticConflicts) > 0 {
+		return r.newCondition(networkingv1alpha1.RandomIngressHostConflict, corev1.ConditionFalse, staticHostsSharedReason,
+			"static hosts also served by other Ingresses: "+staticConflicts.String())
+	}
+
+	if len(conflicts) == 0 {
+		return r.newCondition(networkingv1alpha1.RandomIngressHostConflict, corev1.ConditionFalse, noHostConflictReason,
+			"no host is served by another Ingress")
+	}
+
+	return r.newCondition(networkingv1alpha1.RandomIngressHostConflict, corev1.ConditionTrue, hostsInUseReason,
+		"hosts served by other Ingresses: "+conflicts.String())
+}
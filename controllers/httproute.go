@@ -0,0 +1,169 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+// httpRouteGVK identifies Gateway API HTTPRoutes, which are handled as unstructured objects.
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// httpRouteTarget generates Gateway API HTTPRoutes from spec.httpRouteTemplate.
+// Placeholders are substituted in the hostnames of the routes.
+type httpRouteTarget struct{}
+
+func (httpRouteTarget) base() unstructuredTarget {
+	return unstructuredTarget{gvk: httpRouteGVK}
+}
+
+func (t httpRouteTarget) groupVersionKind() schema.GroupVersionKind {
+	return httpRouteGVK
+}
+
+func (t httpRouteTarget) newObject() client.Object {
+	return t.base().newObject()
+}
+
+func (t httpRouteTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	return t.base().list(ctx, c, opts...)
+}
+
+func (httpRouteTarget) templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata {
+	if spec.HTTPRouteTemplate == nil {
+		return networkingv1alpha1.IngressTemplateMetadata{}
+	}
+
+	return spec.HTTPRouteTemplate.Metadata
+}
+
+func (httpRouteTarget) templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	routeSpec, err := decodeTemplateSpec(spec.HTTPRouteTemplate)
+	if err != nil {
+		return nil
+	}
+
+	hostnames, _, _ := unstructured.NestedStringSlice(routeSpec, "hostnames")
+	return hostnames
+}
+
+func (t httpRouteTarget) templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	return t.templateHosts(spec)
+}
+
+func (t httpRouteTarget) render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error) {
+	routeSpec, err := decodeTemplateSpec(spec.HTTPRouteTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames, _, err := unstructured.NestedStringSlice(routeSpec, "hostnames")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hostnames {
+		hostnames[i] = replacePlaceholders(hostnames[i], tokens)
+	}
+
+	if err := unstructured.SetNestedStringSlice(routeSpec, hostnames, "hostnames"); err != nil {
+		return nil, err
+	}
+
+	return t.base().newUnstructured(meta, routeSpec), nil
+}
+
+// validate checks that the route lists hostnames, which all carry a placeholder: a route without hostnames
+// would match every host of the listeners of its gateways, and a static hostname would not be hidden.
+func (t httpRouteTarget) validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	specPath := field.NewPath("spec", "httpRouteTemplate", "spec")
+	hostnamesPath := specPath.Child("hostnames")
+
+	routeSpec, err := decodeTemplateSpec(spec.HTTPRouteTemplate)
+	if err != nil {
+		return append(errs, field.Invalid(specPath, string(spec.HTTPRouteTemplate.Spec.Raw), err.Error()))
+	}
+
+	hostnames, found, err := unstructured.NestedStringSlice(routeSpec, "hostnames")
+	switch {
+	case err != nil:
+		return append(errs, field.Invalid(hostnamesPath, routeSpec["hostnames"], "must be a list of hostnames"))
+	case !found || len(hostnames) == 0:
+		return append(errs, field.Required(hostnamesPath, "routes must be restricted to random hostnames"))
+	}
+
+	for i, hostname := range hostnames {
+		if !placeholderPattern.MatchString(hostname) {
+			errs = append(errs, field.Invalid(hostnamesPath.Index(i), hostname, randomPlaceholderMissingError))
+		}
+	}
+
+	if r.placeholderTokensValid(spec) {
+		errs = append(errs, validateHostList(spec, hostnames, hostnamesPath.Index, errs)...)
+		errs = append(errs, validateLabels(spec, field.NewPath("spec", "httpRouteTemplate", "metadata", "labels"))...)
+	}
+
+	return errs
+}
+
+func (httpRouteTarget) hosts(obj client.Object) []string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	hostnames, _, _ := unstructured.NestedStringSlice(u.Object, "spec", "hostnames")
+	return hostnames
+}
+
+// servedBy returns the parents, as namespace/name, which accepted the HTTPRoute.
+func (httpRouteTarget) servedBy(obj client.Object) map[string]bool {
+	parents := map[string]bool{}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return parents
+	}
+
+	statuses, _, _ := unstructured.NestedSlice(u.Object, "status", "parents")
+	for _, status := range statuses {
+		parentStatus, ok := status.(map[string]interface{})
+		if !ok || !conditionTrue(parentStatus, "Accepted") {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(parentStatus, "parentRef", "name")
+		namespace, found, _ := unstructured.NestedString(parentStatus, "parentRef", "namespace")
+		if !found {
+			namespace = u.GetNamespace()
+		}
+
+		parents[namespace+"/"+name] = true
+	}
+
+	return parents
+}
+
+// conditionTrue returns true if the conditions of the unstructured status hold the given type with a True status.
+func conditionTrue(status map[string]interface{}, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if ok && fields["type"] == conditionType && fields["status"] == string(metav1.ConditionTrue) {
+			return true
+		}
+	}
+
+	return false
+}
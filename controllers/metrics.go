@@ -0,0 +1,22 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ingressDeletionDelay observes how late expired Ingresses are deleted after their expiration.
+var ingressDeletionDelay = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "randomingress_ingress_deletion_delay_seconds",
+	Help:    "Delay between the expiration of a generated Ingress and its deletion.",
+	Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+})
+
+func init() {
+	metrics.Registry.MustRegister(ingressDeletionDelay)
+}
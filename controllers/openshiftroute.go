@@ -0,0 +1,152 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+// openShiftRouteGVK identifies OpenShift Routes, which are handled as unstructured objects.
+var openShiftRouteGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// openShiftRouteTarget generates OpenShift Routes from spec.openShiftRouteTemplate.
+// A Route serves a single host, in which placeholders are substituted.
+type openShiftRouteTarget struct{}
+
+func (openShiftRouteTarget) base() unstructuredTarget {
+	return unstructuredTarget{gvk: openShiftRouteGVK}
+}
+
+func (t openShiftRouteTarget) groupVersionKind() schema.GroupVersionKind {
+	return openShiftRouteGVK
+}
+
+func (t openShiftRouteTarget) newObject() client.Object {
+	return t.base().newObject()
+}
+
+func (t openShiftRouteTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	return t.base().list(ctx, c, opts...)
+}
+
+func (openShiftRouteTarget) templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata {
+	if spec.OpenShiftRouteTemplate == nil {
+		return networkingv1alpha1.IngressTemplateMetadata{}
+	}
+
+	return spec.OpenShiftRouteTemplate.Metadata
+}
+
+func (openShiftRouteTarget) templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	routeSpec, err := decodeTemplateSpec(spec.OpenShiftRouteTemplate)
+	if err != nil {
+		return nil
+	}
+
+	host, _, _ := unstructured.NestedString(routeSpec, "host")
+	if host == "" {
+		return nil
+	}
+
+	return []string{host}
+}
+
+func (t openShiftRouteTarget) templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	return t.templateHosts(spec)
+}
+
+func (t openShiftRouteTarget) render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error) {
+	routeSpec, err := decodeTemplateSpec(spec.OpenShiftRouteTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, err := unstructured.NestedString(routeSpec, "host")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unstructured.SetNestedField(routeSpec, replacePlaceholders(host, tokens), "host"); err != nil {
+		return nil, err
+	}
+
+	return t.base().newUnstructured(meta, routeSpec), nil
+}
+
+// validate checks that the route has a host carrying a placeholder: without a host, OpenShift would generate one
+// from the name of the Route, which is not hidden.
+func (t openShiftRouteTarget) validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	specPath := field.NewPath("spec", "openShiftRouteTemplate", "spec")
+	hostPath := specPath.Child("host")
+
+	routeSpec, err := decodeTemplateSpec(spec.OpenShiftRouteTemplate)
+	if err != nil {
+		return append(errs, field.Invalid(specPath, string(spec.OpenShiftRouteTemplate.Spec.Raw), err.Error()))
+	}
+
+	host, found, err := unstructured.NestedString(routeSpec, "host")
+	switch {
+	case err != nil:
+		return append(errs, field.Invalid(hostPath, routeSpec["host"], "must be a hostname"))
+	case !found || host == "":
+		return append(errs, field.Required(hostPath, "routes must be restricted to a random host"))
+	case !placeholderPattern.MatchString(host):
+		return append(errs, field.Invalid(hostPath, host, randomPlaceholderMissingError))
+	}
+
+	if r.placeholderTokensValid(spec) {
+		errs = append(errs, validateHostList(spec, []string{host}, func(int) *field.Path { return hostPath }, errs)...)
+		errs = append(errs, validateLabels(spec, field.NewPath("spec", "openShiftRouteTemplate", "metadata", "labels"))...)
+	}
+
+	return errs
+}
+
+func (openShiftRouteTarget) hosts(obj client.Object) []string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	host, _, _ := unstructured.NestedString(u.Object, "spec", "host")
+	if host == "" {
+		return nil
+	}
+
+	return []string{host}
+}
+
+// servedBy returns the names of the routers which admitted the Route.
+func (openShiftRouteTarget) servedBy(obj client.Object) map[string]bool {
+	routers := map[string]bool{}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return routers
+	}
+
+	statuses, _, _ := unstructured.NestedSlice(u.Object, "status", "ingress")
+	for _, status := range statuses {
+		ingressStatus, ok := status.(map[string]interface{})
+		if !ok || !conditionTrue(ingressStatus, "Admitted") {
+			continue
+		}
+
+		if routerName, _, _ := unstructured.NestedString(ingressStatus, "routerName"); routerName != "" {
+			routers[routerName] = true
+		}
+	}
+
+	return routers
+}
@@ -0,0 +1,370 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/token"
+)
+
+// tokensAnnotation records the token substituted to each placeholder of an Ingress, as JSON.
+// The token of the unnamed |RANDOM| placeholder is recorded under the empty name.
+const tokensAnnotation = "networking.backmarket.io/tokens"
+
+// placeholderPattern matches the |RANDOM| placeholder and the named |RANDOM:<name>| placeholders.
+var placeholderPattern = regexp.MustCompile(`\|RANDOM(?::([a-z0-9](?:[-a-z0-9]*[a-z0-9])?))?\|`)
+
+// ingressToken is a token substituted to a placeholder of an Ingress.
+type ingressToken struct {
+	Value     string      `json:"value"`
+	IssuedAt  metav1.Time `json:"issuedAt"`
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// templatePlaceholders returns the names of the placeholders used by the template, sorted: in the hosts, paths and
+// TLS sections of Ingresses, or the substituted fields of other kinds, and in the annotation and label values.
+func templatePlaceholders(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	seen := map[string]bool{}
+	var names []string
+
+	addPlaceholders := func(value string) {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+
+	t := specTarget(spec)
+	for _, value := range t.templateValues(spec) {
+		addPlaceholders(value)
+	}
+
+	metadata := t.templateMetadata(spec)
+	for _, value := range metadata.Annotations {
+		addPlaceholders(value)
+	}
+	for _, value := range metadata.Labels {
+		addPlaceholders(value)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// replacePlaceholders substitutes the given tokens to the placeholders of the value.
+func replacePlaceholders(value string, tokens map[string]ingressToken) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		return tokens[placeholderPattern.FindStringSubmatch(placeholder)[1]].Value
+	})
+}
+
+// validateRulePlaceholders checks that the rule carries a placeholder, either in its host or in every one of its paths,
+// and that its paths remain valid once their placeholders are substituted.
+func validateRulePlaceholders(rule networkingv1.IngressRule, rulePath *field.Path) (errs field.ErrorList) {
+	var paths []networkingv1.HTTPIngressPath
+	if rule.HTTP != nil {
+		paths = rule.HTTP.Paths
+	}
+	pathsPath := rulePath.Child("http", "paths")
+
+	randomPaths := 0
+	for i, path := range paths {
+		if placeholderPattern.MatchString(path.Path) {
+			randomPaths++
+			errs = append(errs, validateSubstitutedPath(path, pathsPath.Index(i).Child("path"))...)
+		}
+	}
+
+	switch {
+	case placeholderPattern.MatchString(rule.Host):
+	case randomPaths == 0:
+		errs = append(errs, field.Invalid(rulePath.Child("host"), rule.Host, randomPlaceholderMissingError))
+	case randomPaths < len(paths):
+		for i, path := range paths {
+			if !placeholderPattern.MatchString(path.Path) {
+				errs = append(errs, field.Invalid(pathsPath.Index(i).Child("path"), path.Path,
+					randomPlaceholderMissingError+", required in every path when the host has none"))
+			}
+		}
+	}
+
+	return errs
+}
+
+// substituteLongestTokens simulates the substitution of the placeholders of the value by the longest tokens
+// of their format. Tokens are made of lowercase letters, digits and dashes, and start and end with a letter or digit.
+func substituteLongestTokens(spec *networkingv1alpha1.RandomIngressSpec, value string) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		settings := token.NewSettings(placeholderToken(spec, placeholderPattern.FindStringSubmatch(placeholder)[1]))
+		return strings.Repeat("a", settings.Length())
+	})
+}
+
+// placeholderTokensValid returns true if the token settings of every placeholder used by the template are valid.
+func (r *RandomIngressReconciler) placeholderTokensValid(spec *networkingv1alpha1.RandomIngressSpec) bool {
+	for _, name := range templatePlaceholders(spec) {
+		if len(r.validateToken(placeholderToken(spec, name), field.NewPath("spec", "token"))) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateLabels checks that the label values of the template remain valid once their placeholders are substituted
+// by the longest tokens of their format.
+func validateLabels(spec *networkingv1alpha1.RandomIngressSpec, labelsPath *field.Path) (errs field.ErrorList) {
+	labels := specTarget(spec).templateMetadata(spec).Labels
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := labels[key]
+		if !placeholderPattern.MatchString(value) {
+			continue
+		}
+
+		for _, msg := range validation.IsValidLabelValue(substituteLongestTokens(spec, value)) {
+			errs = append(errs, field.Invalid(labelsPath.Key(key), value, msg))
+		}
+	}
+
+	return errs
+}
+
+// validateSubstitutedPath checks the path once its placeholders are substituted, following the rules of its path type.
+// Tokens are made of lowercase letters, digits and dashes, so any token gives the same result.
+func validateSubstitutedPath(path networkingv1.HTTPIngressPath, pathPath *field.Path) (errs field.ErrorList) {
+	substituted := placeholderPattern.ReplaceAllString(path.Path, "token")
+
+	if !strings.HasPrefix(substituted, "/") {
+		return append(errs, field.Invalid(pathPath, path.Path, "must be an absolute path"))
+	}
+
+	if path.PathType == nil || *path.PathType == networkingv1.PathTypeImplementationSpecific {
+		return errs
+	}
+
+	for _, invalidSequence := range []string{"//", "/./", "/../", "%2f", "%2F"} {
+		if strings.Contains(substituted, invalidSequence) {
+			errs = append(errs, field.Invalid(pathPath, path.Path, fmt.Sprintf("must not contain '%s'", invalidSequence)))
+		}
+	}
+
+	for _, invalidSuffix := range []string{"/..", "/."} {
+		if strings.HasSuffix(substituted, invalidSuffix) {
+			errs = append(errs, field.Invalid(pathPath, path.Path, fmt.Sprintf("must not end with '%s'", invalidSuffix)))
+		}
+	}
+
+	return errs
+}
+
+// placeholderToken returns the token spec of the named placeholder, falling back to spec.token.
+func placeholderToken(spec *networkingv1alpha1.RandomIngressSpec, name string) *networkingv1alpha1.TokenSpec {
+	for _, placeholder := range spec.Placeholders {
+		if placeholder.Name == name && placeholder.Token != nil {
+			return placeholder.Token
+		}
+	}
+
+	return spec.Token
+}
+
+// forPlaceholder returns the rotation settings of the tokens of the named placeholder.
+func (s rotationSettings) forPlaceholder(name string) rotationSettings {
+	if settings, ok := s.placeholders[name]; ok {
+		return settings
+	}
+
+	return s
+}
+
+// tokenEntropyBits returns the lowest entropy among the tokens of the placeholders used by the template,
+// or the entropy of spec.token if the template uses none.
+func tokenEntropyBits(spec *networkingv1alpha1.RandomIngressSpec) int32 {
+	entropy := int32(token.NewSettings(spec.Token).Entropy())
+	for i, name := range templatePlaceholders(spec) {
+		if placeholderEntropy := int32(token.NewSettings(placeholderToken(spec, name)).Entropy()); i == 0 || placeholderEntropy < entropy {
+			entropy = placeholderEntropy
+		}
+	}
+
+	return entropy
+}
+
+// ingressTokens returns the tokens recorded on the ingress, if any.
+func ingressTokens(ingress client.Object) (map[string]ingressToken, bool) {
+	value, ok := ingress.GetAnnotations()[tokensAnnotation]
+	if !ok {
+		return nil, false
+	}
+
+	var tokens map[string]ingressToken
+	if err := json.Unmarshal([]byte(value), &tokens); err != nil || len(tokens) == 0 {
+		return nil, false
+	}
+
+	// Like the other annotations, times are handled in UTC rather than in the local time unmarshalled by metav1.Time.
+	for name, ingressToken := range tokens {
+		ingressToken.IssuedAt = metav1.NewTime(ingressToken.IssuedAt.UTC())
+		ingressToken.ExpiresAt = metav1.NewTime(ingressToken.ExpiresAt.UTC())
+		tokens[name] = ingressToken
+	}
+
+	return tokens, true
+}
+
+// tokenScheduledExpiresAt returns the time at which the token is scheduled to expire: the expiration recorded
+// at creation, or earlier if the lifetime of its placeholder has been changed since.
+func tokenScheduledExpiresAt(ingressToken ingressToken, settings rotationSettings) time.Time {
+	expiresAt := settings.expiresAt(ingressToken.IssuedAt.Time)
+	if ingressToken.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = ingressToken.ExpiresAt.Time
+	}
+
+	return expiresAt
+}
+
+// generateTokens returns the tokens of a new Ingress issued at the given time. The tokens of the previous Ingress
+// which do not expire within the handover duration are kept, so that each placeholder rotates on its own.
+func (r *RandomIngressReconciler) generateTokens(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, settings rotationSettings, issuedAt time.Time, previous client.Object) (map[string]ingressToken, error) {
+	var previousTokens map[string]ingressToken
+	if previous != nil && (settings.rotationRequest == nil || previous.GetAnnotations()[rotationTokenAnnotation] == settings.rotationRequest.Token) {
+		previousTokens, _ = ingressTokens(previous)
+	}
+
+	tokens := map[string]ingressToken{}
+	for _, name := range templatePlaceholders(&randomIngress.Spec) {
+		placeholderSettings := settings.forPlaceholder(name)
+
+		if previousToken, ok := previousTokens[name]; ok {
+			expiresAt := tokenScheduledExpiresAt(previousToken, placeholderSettings)
+			if issuedAt.Before(expiresAt.Add(-placeholderSettings.handoverDuration)) {
+				tokens[name] = previousToken
+				continue
+			}
+		}
+
+		tokenSource, err := r.tokenSource(ctx, randomIngress, name, placeholderSettings, issuedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := r.newToken(tokenSource, token.NewSettings(placeholderToken(&randomIngress.Spec, name)), randomIngress.Status.TokenHistory, issuedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens[name] = ingressToken{
+			Value:     value,
+			IssuedAt:  metav1.NewTime(issuedAt),
+			ExpiresAt: metav1.NewTime(placeholderSettings.expiresAt(issuedAt)),
+		}
+	}
+
+	return tokens, nil
+}
+
+// tokensHash returns a hash of the values of the tokens, taken in the order of their names.
+// It is part of the Ingress name to avoid collisions with previous instances of the Ingress that have the same template.
+func tokensHash(tokens map[string]ingressToken) []byte {
+	names := make([]string, 0, len(tokens))
+	for name := range tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tokenHasher := fnv.New32a()
+	for _, name := range names {
+		tokenHasher.Write([]byte(tokens[name].Value))
+	}
+
+	return tokenHasher.Sum(nil)
+}
+
+// tokensStatus reports the current values of the named placeholders of the ingress, sorted by name.
+func tokensStatus(ingress client.Object, settings rotationSettings) []networkingv1alpha1.TokenStatus {
+	tokens, _ := ingressTokens(ingress)
+
+	var statuses []networkingv1alpha1.TokenStatus
+	for name, ingressToken := range tokens {
+		if name == "" {
+			continue
+		}
+
+		statuses = append(statuses, networkingv1alpha1.TokenStatus{
+			Name:      name,
+			Value:     ingressToken.Value,
+			ExpiresAt: metav1.NewTime(tokenScheduledExpiresAt(ingressToken, settings.forPlaceholder(name))),
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses
+}
+
+// validatePlaceholders checks the named placeholders configured in the spec.
+func (r *RandomIngressReconciler) validatePlaceholders(spec *networkingv1alpha1.RandomIngressSpec, settings rotationSettings) (errs field.ErrorList) {
+	placeholdersPath := field.NewPath("spec", "placeholders")
+
+	used := map[string]bool{}
+	for _, name := range templatePlaceholders(spec) {
+		used[name] = true
+	}
+
+	declared := map[string]bool{}
+	for i, placeholder := range spec.Placeholders {
+		placeholderPath := placeholdersPath.Index(i)
+
+		switch {
+		case declared[placeholder.Name]:
+			errs = append(errs, field.Duplicate(placeholderPath.Child("name"), placeholder.Name))
+		case placeholder.Name == "" || !used[placeholder.Name]:
+			errs = append(errs, field.Invalid(placeholderPath.Child("name"), placeholder.Name, "is not used by the template"))
+		}
+		declared[placeholder.Name] = true
+
+		if placeholder.MaxLifetime != nil {
+			errs = append(errs, r.validateMaxLifetime(placeholder.MaxLifetime.Duration, placeholderPath.Child("maxLifetime"))...)
+
+			if handover := settings.handoverDuration; placeholder.MaxLifetime.Duration <= handover {
+				errs = append(errs, field.Invalid(placeholderPath.Child("maxLifetime"), placeholder.MaxLifetime.Duration.String(),
+					fmt.Sprintf("must be longer than the handover duration (%s)", handover)))
+			}
+		}
+
+		if placeholder.Token != nil {
+			errs = append(errs, r.validateToken(placeholder.Token, placeholderPath.Child("token"))...)
+		}
+	}
+
+	return errs
+}
@@ -8,19 +8,18 @@ package controllers
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"hash/fnv"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	corev1 "k8s.io/api/core/v1"
-	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
-	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,6 +27,7 @@ import (
 
 	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
 	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/hash"
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/token"
 )
 
 const (
@@ -37,6 +37,29 @@ const (
 	specValidReason   = "SpecValid"
 	specInvalidReason = "SpecInvalid"
 	specValidMessage  = "spec is valid"
+
+	newIngressCreatedReason  = "NewIngressCreated"
+	handoverInProgressReason = "HandoverInProgress"
+	oldIngressDeletedReason  = "OldIngressDeleted"
+	singleIngressLiveReason  = "SingleIngressLive"
+	noIngressLiveReason      = "NoIngressLive"
+
+	rotationSuspendedReason = "RotationSuspended"
+	rotationActiveReason    = "RotationActive"
+
+	blackoutWindowReason = "BlackoutWindow"
+	notDeferredReason    = "NotDeferred"
+
+	createFailedReason         = "CreateFailed"
+	noReplacementFailureReason = "NoFailure"
+
+	// issuedAtAnnotation and expiresAtAnnotation are stamped on every generated Ingress,
+	// in RFC 3339 format. Unlike the creation timestamp, they survive a backup and restore.
+	issuedAtAnnotation  = "networking.backmarket.io/issued-at"
+	expiresAtAnnotation = "networking.backmarket.io/expires-at"
+
+	// rotationTokenAnnotation records the token of the rotation request an Ingress was generated for.
+	rotationTokenAnnotation = "networking.backmarket.io/rotation-token"
 )
 
 // RandomIngressReconciler reconciles a RandomIngress object
@@ -45,8 +68,84 @@ type RandomIngressReconciler struct {
 	Scheme                  *runtime.Scheme
 	IngressMaxLifetime      time.Duration
 	IngressHandoverDuration time.Duration
-	Clock                   Clock
-	UUIDSource              UUIDSource
+
+	// IngressLifetimeLowerBound and IngressLifetimeUpperBound restrict the lifetimes
+	// that RandomIngresses can request through spec.maxLifetime. Zero means no bound.
+	IngressLifetimeLowerBound time.Duration
+	IngressLifetimeUpperBound time.Duration
+
+	// BlackoutWindows are the operator-wide periods during which rotations are postponed,
+	// by at most MaxRotationDeferral. Zero means no limit.
+	BlackoutWindows     []networkingv1alpha1.BlackoutWindow
+	MaxRotationDeferral time.Duration
+
+	// ReadinessGateMaxExtension is the default of spec.readinessGate.maxExtension.
+	ReadinessGateMaxExtension time.Duration
+
+	// MinTokenEntropyBits is the minimum entropy of the random tokens that RandomIngresses can request.
+	MinTokenEntropyBits int
+
+	// MinWordsTokenEntropyBits replaces MinTokenEntropyBits for words tokens,
+	// which cannot give as many random bits within a DNS label.
+	MinWordsTokenEntropyBits int
+
+	// DetectHostConflicts makes the reconciler look for other Ingresses serving the hosts of RandomIngresses,
+	// through an index on the hosts of all Ingresses of the cluster.
+	DetectHostConflicts bool
+
+	// EnableHTTPRoutes allows RandomIngresses to generate Gateway API HTTPRoutes instead of Ingresses.
+	// Their CRDs must be installed in the cluster.
+	EnableHTTPRoutes bool
+
+	// EnableOpenShiftRoutes allows RandomIngresses to generate OpenShift Routes instead of Ingresses.
+	EnableOpenShiftRoutes bool
+
+	// EnableVirtualServices allows RandomIngresses to generate Istio VirtualServices instead of Ingresses,
+	// and to update the hosts of Istio Gateways.
+	EnableVirtualServices bool
+
+	// EnableTraefikIngressRoutes allows RandomIngresses to generate Traefik IngressRoutes instead of Ingresses.
+	EnableTraefikIngressRoutes bool
+
+	// TokenHistorySize is the number of issued tokens recorded in the status of each RandomIngress,
+	// so that none of them is issued again. Zero disables the token history.
+	TokenHistorySize int
+
+	// APIReader reads the Secrets of derived tokens from the API server,
+	// so that the operator neither caches nor watches every Secret of the cluster.
+	APIReader client.Reader
+
+	Clock       Clock
+	TokenSource token.Source
+}
+
+// rotationSettings are the effective rotation settings of a RandomIngress,
+// once the operator defaults have been applied.
+type rotationSettings struct {
+	// maxLifetime caps the lifetime of the Ingresses. Zero means no cap, for scheduled rotations only.
+	maxLifetime      time.Duration
+	handoverDuration time.Duration
+
+	// schedule, if set, gives the wall-clock times at which Ingresses expire.
+	schedule cron.Schedule
+
+	// blackoutWindows postpone the expiration of Ingresses by at most maxDeferral, unless zero.
+	blackoutWindows []networkingv1alpha1.BlackoutWindow
+	maxDeferral     time.Duration
+
+	// readinessGate, if set, keeps expired Ingresses alive until their replacement is served.
+	readinessGate *readinessGate
+
+	// epochAligned makes Ingresses expire at the end of epochs of maxLifetime counted from the Unix epoch,
+	// so that operators deriving tokens from the same secret rotate them at the same time.
+	epochAligned bool
+
+	// placeholders are the settings of the tokens of the named placeholders configured in the spec.
+	placeholders map[string]rotationSettings
+
+	// rotationRequest is the pending rotation request, if any.
+	// Ingresses generated before it expire early.
+	rotationRequest *networkingv1alpha1.RotationRequestStatus
 }
 
 type realClock struct{}
@@ -59,21 +158,17 @@ type Clock interface {
 	Now() time.Time
 }
 
-// UUIDSource represents a source of random UUIDs.
-// It can be used in tests to predetermine the returned UUIDs.
-type UUIDSource interface {
-	NewUUID() types.UID
-}
-
-type realUUIDSource struct{}
-
-func (realUUIDSource) NewUUID() types.UID { return uuid.NewUUID() }
-
 //+kubebuilder:rbac:groups=networking.backmarket.io,resources=randomingresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.backmarket.io,resources=randomingresses/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=networking.backmarket.io,resources=randomingresses/finalizers,verbs=update
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create
+//+kubebuilder:rbac:groups=networking.istio.io,resources=virtualservices,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.istio.io,resources=gateways,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=traefik.io,resources=ingressroutes,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -97,10 +192,28 @@ func (r *RandomIngressReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	randomIngress = *randomIngress.DeepCopy()
+
+	if !randomIngress.DeletionTimestamp.IsZero() {
+		// Generated objects are garbage collected, only the hosts added to a Gateway must be removed.
+		if err := r.finalizeGatewayHosts(ctx, &randomIngress); err != nil {
+			logger.Error(err, "failed to remove Gateway hosts of deleted RandomIngress")
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.ensureGatewayHostsFinalizer(ctx, &randomIngress); err != nil {
+		logger.Error(err, "failed to add Gateway hosts finalizer")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
 	logger.Info("Start processing")
 
 	randomIngress.Status.NextRenewalTime = nil
-	validationErrors := validateSpec(&randomIngress.Spec)
+	settings := r.rotationSettings(&randomIngress.Spec)
+	settings.rotationRequest = r.observeRotationRequest(&randomIngress)
+	validationErrors := r.validateSpec(&randomIngress.Spec)
 	if validationErrors != nil {
 		message := validationErrors.ToAggregate().Error()
 
@@ -113,141 +226,667 @@ func (r *RandomIngressReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		setCondition(&randomIngress.Status, validCondition)
 	}
 
-	var ownedIngresses networkingv1.IngressList
-	err := r.Client.List(ctx, &ownedIngresses, client.InNamespace(req.Namespace), client.MatchingFields{ingressOwnerKey: req.Name})
+	randomIngress.Status.TokenEntropyBits = tokenEntropyBits(&randomIngress.Spec)
+	setCondition(&randomIngress.Status, r.perHostCertificateCondition(&randomIngress.Spec))
+
+	var staticConflicts hostConflicts
+	if r.DetectHostConflicts {
+		var err error
+		if staticConflicts, err = r.findHostConflicts(ctx, &randomIngress, staticHosts(&randomIngress.Spec)); err != nil {
+			logger.Error(err, "failed to look for host conflicts")
+			return ctrl.Result{}, err
+		}
+	}
+
+	suspended := r.rotationSuspended(&randomIngress.Spec)
+	setCondition(&randomIngress.Status, r.suspendedCondition(&randomIngress.Spec, suspended))
+
+	ownedIngresses, err := r.listOwnedObjects(ctx, req.Namespace, req.Name)
 	if err != nil {
 		logger.Error(err, "failed to list owned Ingresses")
 		return ctrl.Result{}, err
 	}
 
-	specHash := hash.RandomIngressSpec(&randomIngress.Spec)
+	specHashes := templateHashes(&randomIngress.Spec)
+	specHash := specHashes[0]
 
-	var expiredIngresses []*networkingv1.Ingress
-	var fullyAliveIngresses []*networkingv1.Ingress
+	var expiredIngresses []client.Object
+	var aliveIngresses []client.Object
+	var fullyAliveIngresses []client.Object
 
-	for i := range ownedIngresses.Items {
+	for _, ingress := range ownedIngresses {
 		switch {
-		case !ingressMatchesSpec(&ownedIngresses.Items[i], specHash),
-			r.ingressExpired(&ownedIngresses.Items[i]):
-			expiredIngresses = append(expiredIngresses, &ownedIngresses.Items[i])
-		case r.ingressExpiringSoon(&ownedIngresses.Items[i]):
-			// We just need to exclude them from alive Ingresses so that they don't block
+		case !ingressMatchesSpec(ingress, specHashes),
+			r.ingressExpired(ingress, settings):
+			expiredIngresses = append(expiredIngresses, ingress)
+		case r.ingressExpiringSoon(ingress, settings):
+			// We just need to exclude them from fully alive Ingresses so that they don't block
 			// new Ingress creation.
+			aliveIngresses = append(aliveIngresses, ingress)
 		default:
-			fullyAliveIngresses = append(fullyAliveIngresses, &ownedIngresses.Items[i])
+			aliveIngresses = append(aliveIngresses, ingress)
+			fullyAliveIngresses = append(fullyAliveIngresses, ingress)
+		}
+	}
+
+	expiredIngresses, heldIngresses := r.holdExpiredIngresses(expiredIngresses, aliveIngresses, specHashes, settings)
+
+	if suspended {
+		// Keep every Ingress as is until the rotation resumes.
+		logger.Info("rotation suspended")
+		expiredIngresses = nil
+		heldIngresses = nil
+	}
+
+	var newIngress client.Object = nil
+	var replacementErr error
+	var keptIngress client.Object
+	var conflicts hostConflicts
+
+	if len(fullyAliveIngresses) == 0 && len(validationErrors) == 0 && !suspended {
+		// Make before break: the expired Ingresses are deleted only once their replacement is created.
+		// Alive Ingresses all match the spec, the newest one holds the tokens which are not expiring yet.
+		newIngress, conflicts, replacementErr = r.newIngress(ctx, &randomIngress, specHash, settings, newestIngress(aliveIngresses))
+		if replacementErr != nil {
+			logger.Error(replacementErr, "failed to create new Ingress")
+		} else if replacementErr = r.Client.Create(ctx, newIngress); replacementErr != nil {
+			logger.Error(replacementErr, "failed to create Ingress for RandomIngress", "ingressName", newIngress.GetName())
+		}
+
+		if replacementErr != nil {
+			newIngress = nil
+		}
+	}
+
+	// Without a replacement, because the spec is invalid or its creation failed, the last serving Ingress is kept.
+	if newIngress == nil && len(aliveIngresses) == 0 && len(heldIngresses) == 0 {
+		expiredIngresses, keptIngress = keepLastServingIngress(expiredIngresses)
+		if keptIngress != nil {
+			logger.Info("keeping the last serving Ingress until it is replaced", "ingressName", keptIngress.GetName())
 		}
 	}
+	setCondition(&randomIngress.Status, r.replacementFailedCondition(replacementErr, keptIngress))
+	setCondition(&randomIngress.Status, r.hostConflictCondition(staticConflicts, conflicts))
 
+	deletedIngresses := map[string]bool{}
 	for _, ingress := range expiredIngresses {
 		err := r.Client.Delete(ctx, ingress)
 		if client.IgnoreNotFound(err) != nil {
-			logger.Error(err, "failed to delete expired Ingress", "ingressName", ingress.Name)
+			logger.Error(err, "failed to delete expired Ingress", "ingressName", ingress.GetName())
 		} else {
-			logger.Info("deleted expired Ingress", "ingressName", ingress.Name)
-		}
-	}
-
-	var newIngress *networkingv1.Ingress = nil
+			logger.Info("deleted expired Ingress", "ingressName", ingress.GetName())
+			deletedIngresses[ingress.GetName()] = true
 
-	if len(fullyAliveIngresses) == 0 && len(validationErrors) == 0 {
-		newIngress, err = r.createIngress(&randomIngress, specHash)
-		if err != nil {
-			logger.Error(err, "failed to create new Ingress")
-			return ctrl.Result{}, err
+			if ingressMatchesSpec(ingress, specHashes) {
+				r.recordIngressDeletion(&randomIngress.Status, ingress, settings)
+			}
 		}
 	}
 
 	if newIngress != nil {
-		err := r.Client.Create(ctx, newIngress)
-		if err != nil {
-			logger.Error(err, "failed to create Ingress for RandomIngress", "ingressName", newIngress.Name)
-			return ctrl.Result{}, err
-		}
-
-		nextRenewalTime := metav1.NewTime(r.Clock.Now().Add(r.IngressMaxLifetime))
+		nextRenewalTime := metav1.NewTime(ingressExpiresAt(newIngress, settings))
 		randomIngress.Status.NextRenewalTime = &nextRenewalTime
-	} else {
+	} else if !suspended {
 		if len(fullyAliveIngresses) > 0 {
 			sort.Slice(fullyAliveIngresses, func(i, j int) bool {
 				// Want the highest timestamp first
-				return fullyAliveIngresses[i].CreationTimestamp.After(fullyAliveIngresses[j].CreationTimestamp.Time)
+				return ingressIssuedAt(fullyAliveIngresses[i]).After(ingressIssuedAt(fullyAliveIngresses[j]))
 			})
 
-			nextRenewalTime := metav1.NewTime(fullyAliveIngresses[0].CreationTimestamp.Time.Add(r.IngressMaxLifetime))
+			nextRenewalTime := metav1.NewTime(ingressExpiresAt(fullyAliveIngresses[0], settings))
 			randomIngress.Status.NextRenewalTime = &nextRenewalTime
 		}
 	}
 
+	var liveIngresses []client.Object
+	for _, ingress := range ownedIngresses {
+		if !deletedIngresses[ingress.GetName()] {
+			liveIngresses = append(liveIngresses, ingress)
+		}
+	}
+	if newIngress != nil {
+		liveIngresses = append(liveIngresses, newIngress)
+	}
+
+	if r.TokenHistorySize > 0 {
+		// Deleted Ingresses are recorded too: their tokens are the expired ones the history is meant to reject.
+		issuedIngresses := ownedIngresses
+		if newIngress != nil {
+			issuedIngresses = append(append([]client.Object(nil), ownedIngresses...), newIngress)
+		}
+		if err := r.recordIngressTokens(&randomIngress.Status, issuedIngresses); err != nil {
+			logger.Error(err, "failed to record issued tokens")
+		}
+	}
+
+	replacement := newIngress
+	if replacement == nil {
+		replacement = newestIngress(aliveIngresses)
+	}
+	if len(heldIngresses) > 0 {
+		setCondition(&randomIngress.Status, r.heldIngressesProgressingCondition(replacement, heldIngresses))
+	} else {
+		setCondition(&randomIngress.Status, r.progressingCondition(len(liveIngresses), newIngress, len(deletedIngresses)))
+	}
+	setCondition(&randomIngress.Status, r.newIngressNotReadyCondition(settings, replacement, heldIngresses))
+	r.setActiveIngresses(&randomIngress.Status, liveIngresses, settings)
+
+	gatewayErr := r.syncGatewayHosts(ctx, &randomIngress, liveIngresses)
+	if gatewayErr != nil {
+		logger.Error(gatewayErr, "failed to sync Gateway hosts")
+	}
+
+	// Live Ingresses are now sorted newest first: the next rotation is the one of the newest.
+	var newestIngress client.Object
+	if len(liveIngresses) > 0 {
+		newestIngress = liveIngresses[0]
+	}
+	setCondition(&randomIngress.Status, r.rotationDeferredCondition(newestIngress, settings))
+
 	if err := r.Client.Status().Update(ctx, &randomIngress); err != nil {
 		logger.Error(err, "failed to update Status")
 
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if replacementErr != nil {
+		// Retry with backoff, the last serving Ingress is kept meanwhile.
+		return ctrl.Result{}, replacementErr
+	}
+
+	if gatewayErr != nil {
+		// Retry with backoff, the Gateway may still serve hosts of deleted VirtualServices meanwhile.
+		return ctrl.Result{}, gatewayErr
+	}
+
 	result := ctrl.Result{}
-	if randomIngress.Status.NextRenewalTime != nil {
-		// TODO: we need to compute this from oldest Ingress creation time if there's one,
-		// or we might not delete it in time.
-		result.RequeueAfter = randomIngress.Status.NextRenewalTime.Time.Sub(r.Clock.Now()) - r.IngressHandoverDuration
+	if suspended {
+		if randomIngress.Spec.SuspendUntil != nil {
+			result.RequeueAfter = randomIngress.Spec.SuspendUntil.Time.Sub(r.Clock.Now())
+		}
+	} else if nextEvent := r.nextRotationEvent(liveIngresses, settings); !nextEvent.IsZero() {
+		result.RequeueAfter = nextEvent.Sub(r.Clock.Now())
 	}
 
 	logger.WithValues("requeueAfter", result.RequeueAfter).Info("Processed succesfully")
 	return result, nil
 }
 
-func validateSpec(spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
-	rulesPath := field.NewPath("spec", "ingressTemplate", "spec", "rules")
+func (r *RandomIngressReconciler) validateSpec(spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	errs = append(errs, r.validateTemplate(spec)...)
+
+	settings := r.rotationSettings(spec)
+
+	if spec.MaxLifetime != nil {
+		errs = append(errs, r.validateMaxLifetime(settings.maxLifetime, field.NewPath("spec", "maxLifetime"))...)
+	}
+
+	handoverPath := field.NewPath("spec", "handoverDuration")
+	switch {
+	// A schedule lifts the cap on the lifetime when no upper bound is configured: there is no max lifetime to compare with.
+	case (settings.schedule == nil || settings.maxLifetime > 0) &&
+		(settings.handoverDuration < 0 || settings.handoverDuration >= settings.maxLifetime):
+		errs = append(errs, field.Invalid(handoverPath, settings.handoverDuration.String(),
+			fmt.Sprintf("must not be negative and must be shorter than the max lifetime (%s)", settings.maxLifetime)))
+	case settings.handoverDuration < 0:
+		errs = append(errs, field.Invalid(handoverPath, settings.handoverDuration.String(), "must not be negative"))
+	}
+
+	if settings.readinessGate != nil && settings.readinessGate.maxExtension <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "readinessGate", "maxExtension"),
+			settings.readinessGate.maxExtension.String(), "must be positive"))
+	}
+
+	errs = append(errs, r.validateToken(spec.Token, field.NewPath("spec", "token"))...)
+	errs = append(errs, r.validatePlaceholders(spec, settings)...)
+
+	if spec.RotationSchedule != nil {
+		for _, name := range templatePlaceholders(spec) {
+			if tokenDerived(placeholderToken(spec, name)) {
+				errs = append(errs, field.Forbidden(field.NewPath("spec", "rotationSchedule"), "must not be set along with derived tokens"))
+				break
+			}
+		}
+	}
+
+	errs = append(errs, validateBlackoutWindows(spec.BlackoutWindows, field.NewPath("spec", "blackoutWindows"))...)
+
+	if spec.RotationSchedule != nil {
+		schedulePath := field.NewPath("spec", "rotationSchedule")
 
-	for i, rule := range spec.IngressTemplate.Spec.Rules {
-		if !strings.Contains(rule.Host, randomPlaceholder) {
-			hostPath := rulesPath.Index(i).Child("host")
-			errs = append(errs, field.Invalid(hostPath, rule.Host, randomPlaceholderMissingError))
+		schedule, err := parseRotationSchedule(spec.RotationSchedule, schedulePath)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			interval := shortestScheduleInterval(schedule, r.Clock.Now())
+
+			switch {
+			case interval == 0:
+				errs = append(errs, field.Invalid(schedulePath.Child("cron"), spec.RotationSchedule.Cron, "never fires"))
+			case r.IngressLifetimeLowerBound > 0 && interval < r.IngressLifetimeLowerBound:
+				errs = append(errs, field.Invalid(schedulePath.Child("cron"), spec.RotationSchedule.Cron,
+					fmt.Sprintf("rotations must be at least %s apart", r.IngressLifetimeLowerBound)))
+			}
 		}
 	}
 
 	return errs
 }
 
-func ingressMatchesSpec(ingress *networkingv1.Ingress, expectedSpecHash string) bool {
-	nameParts := strings.Split(ingress.Name, "-")
+// validateMaxLifetime checks that the given max lifetime is positive and lies within the bounds configured on the operator.
+func (r *RandomIngressReconciler) validateMaxLifetime(maxLifetime time.Duration, maxLifetimePath *field.Path) (errs field.ErrorList) {
+	switch {
+	case maxLifetime <= 0:
+		errs = append(errs, field.Invalid(maxLifetimePath, maxLifetime.String(), "must be positive"))
+	case r.IngressLifetimeLowerBound > 0 && maxLifetime < r.IngressLifetimeLowerBound:
+		errs = append(errs, field.Invalid(maxLifetimePath, maxLifetime.String(),
+			fmt.Sprintf("must be at least %s", r.IngressLifetimeLowerBound)))
+	case r.IngressLifetimeUpperBound > 0 && maxLifetime > r.IngressLifetimeUpperBound:
+		errs = append(errs, field.Invalid(maxLifetimePath, maxLifetime.String(),
+			fmt.Sprintf("must be at most %s", r.IngressLifetimeUpperBound)))
+	}
+
+	return errs
+}
+
+func (r *RandomIngressReconciler) validateToken(spec *networkingv1alpha1.TokenSpec, tokenPath *field.Path) (errs field.ErrorList) {
+	settings := token.NewSettings(spec)
+
+	// The field setting the entropy of the token, reported when it is too low or too high.
+	entropyPath, entropyValue := tokenPath.Child("format"), interface{}(settings.Format)
+
+	if spec != nil {
+		words := settings.Format == networkingv1alpha1.TokenFormatWords
+
+		switch {
+		case settings.Format == networkingv1alpha1.TokenFormatUUIDv4 && spec.EntropyBits != nil:
+			errs = append(errs, field.Invalid(tokenPath.Child("entropyBits"), *spec.EntropyBits, "must not be set for uuidv4 tokens"))
+		case words && spec.EntropyBits != nil && spec.WordCount != nil:
+			errs = append(errs, field.Invalid(tokenPath.Child("entropyBits"), *spec.EntropyBits, "must not be set along with wordCount"))
+		case spec.EntropyBits != nil:
+			entropyPath, entropyValue = tokenPath.Child("entropyBits"), *spec.EntropyBits
+		}
+
+		if spec.WordCount != nil {
+			if words {
+				entropyPath, entropyValue = tokenPath.Child("wordCount"), *spec.WordCount
+			} else {
+				errs = append(errs, field.Invalid(tokenPath.Child("wordCount"), *spec.WordCount, "must only be set for words tokens"))
+			}
+		}
+
+		if spec.Separator != nil && !words {
+			errs = append(errs, field.Invalid(tokenPath.Child("separator"), *spec.Separator, "must only be set for words tokens"))
+		}
+
+		if spec.DerivedFrom != nil && spec.DerivedFrom.SecretKeyRef.Name == "" {
+			errs = append(errs, field.Required(tokenPath.Child("derivedFrom", "secretKeyRef", "name"), ""))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	minEntropy := r.MinTokenEntropyBits
+	if settings.Format == networkingv1alpha1.TokenFormatWords {
+		minEntropy = r.MinWordsTokenEntropyBits
+	}
+
+	entropy := int(settings.Entropy())
+	switch {
+	case entropy <= 0:
+		errs = append(errs, field.Invalid(entropyPath, entropyValue, "must be positive"))
+	case entropy < minEntropy:
+		errs = append(errs, field.Invalid(entropyPath, entropyValue,
+			fmt.Sprintf("gives %d bits of entropy, must give at least %d", entropy, minEntropy)))
+	case settings.Length() > validation.DNS1123LabelMaxLength:
+		errs = append(errs, field.Invalid(entropyPath, entropyValue,
+			fmt.Sprintf("must fit in a DNS label of %d characters", validation.DNS1123LabelMaxLength)))
+	}
+
+	return errs
+}
+
+// rotationSuspended returns true if the rotation of the RandomIngress is currently paused.
+func (r *RandomIngressReconciler) rotationSuspended(spec *networkingv1alpha1.RandomIngressSpec) bool {
+	return spec.Suspend && (spec.SuspendUntil == nil || r.Clock.Now().Before(spec.SuspendUntil.Time))
+}
+
+// suspendedCondition reports whether the rotation of the RandomIngress is paused.
+func (r *RandomIngressReconciler) suspendedCondition(spec *networkingv1alpha1.RandomIngressSpec, suspended bool) networkingv1alpha1.RandomIngressCondition {
+	switch {
+	case suspended && spec.SuspendUntil != nil:
+		message := fmt.Sprintf("rotation is suspended until %s", spec.SuspendUntil.UTC().Format(time.RFC3339))
+		return r.newCondition(networkingv1alpha1.RandomIngressSuspended, corev1.ConditionTrue, rotationSuspendedReason, message)
+	case suspended:
+		return r.newCondition(networkingv1alpha1.RandomIngressSuspended, corev1.ConditionTrue, rotationSuspendedReason, "rotation is suspended")
+	default:
+		return r.newCondition(networkingv1alpha1.RandomIngressSuspended, corev1.ConditionFalse, rotationActiveReason, "rotation is active")
+	}
+}
+
+// expiresAt returns the nominal expiration time of an Ingress issued at the given time.
+// With a schedule, it is the first tick that leaves at least the handover duration to the Ingress.
+func (s rotationSettings) expiresAt(issuedAt time.Time) time.Time {
+	if s.epochAligned {
+		return time.Unix(0, (s.epoch(issuedAt)+1)*int64(s.maxLifetime)).UTC()
+	}
+
+	if s.schedule == nil {
+		return issuedAt.Add(s.maxLifetime)
+	}
+
+	expiresAt := s.schedule.Next(issuedAt.Add(s.handoverDuration))
+	if s.maxLifetime > 0 && issuedAt.Add(s.maxLifetime).Before(expiresAt) {
+		expiresAt = issuedAt.Add(s.maxLifetime)
+	}
+
+	return expiresAt
+}
+
+// epoch returns the index of the epoch an Ingress issued at the given time is generated for:
+// the current one, or the next one if it starts within the handover duration.
+// Without a positive max lifetime, reported by validateSpec, every Ingress falls in the first epoch.
+func (s rotationSettings) epoch(issuedAt time.Time) int64 {
+	if s.maxLifetime <= 0 {
+		return 0
+	}
+
+	return issuedAt.Add(s.handoverDuration).UnixNano() / int64(s.maxLifetime)
+}
+
+// keepLastServingIngress removes the most recently issued Ingress from the expired ones,
+// so that it keeps serving until a replacement is created.
+func keepLastServingIngress(expired []client.Object) (deleted []client.Object, kept client.Object) {
+	kept = newestIngress(expired)
+	for _, ingress := range expired {
+		if ingress != kept {
+			deleted = append(deleted, ingress)
+		}
+	}
+
+	return deleted, kept
+}
+
+// replacementFailedCondition reports whether the Ingress replacing the expired ones could be created.
+func (r *RandomIngressReconciler) replacementFailedCondition(replacementErr error, kept client.Object) networkingv1alpha1.RandomIngressCondition {
+	if replacementErr == nil {
+		return r.newCondition(networkingv1alpha1.RandomIngressReplacementFailed, corev1.ConditionFalse, noReplacementFailureReason, "no replacement failed")
+	}
+
+	message := fmt.Sprintf("failed to create a new Ingress: %v", replacementErr)
+	if kept != nil {
+		message += fmt.Sprintf(", keeping Ingress %s", kept.GetName())
+	}
+
+	return r.newCondition(networkingv1alpha1.RandomIngressReplacementFailed, corev1.ConditionTrue, createFailedReason, message)
+}
+
+// nextRotationEvent returns the earliest upcoming handover start or expiration among the given Ingresses,
+// or the zero time if there is none.
+func (r *RandomIngressReconciler) nextRotationEvent(ingresses []client.Object, settings rotationSettings) time.Time {
+	now := r.Clock.Now()
+
+	var nextEvent time.Time
+	for _, ingress := range ingresses {
+		expiresAt := ingressExpiresAt(ingress, settings)
+
+		events := []time.Time{expiresAt.Add(-settings.handoverDuration), expiresAt}
+		if settings.readinessGate != nil {
+			events = append(events, expiresAt.Add(settings.readinessGate.maxExtension))
+		}
+
+		for _, event := range events {
+			if event.After(now) && (nextEvent.IsZero() || event.Before(nextEvent)) {
+				nextEvent = event
+			}
+		}
+	}
+
+	return nextEvent
+}
+
+// recordIngressDeletion records how late the given expired Ingress was deleted, in the status and in metrics.
+// With a readiness gate, expired Ingresses are deliberately kept until their replacement is served: their deadline
+// is the end of the maximum extension, so that the time spent waiting for the replacement is not counted as a delay.
+func (r *RandomIngressReconciler) recordIngressDeletion(status *networkingv1alpha1.RandomIngressStatus, ingress client.Object, settings rotationSettings) {
+	now := r.Clock.Now()
+	expiresAt := ingressExpiresAt(ingress, settings)
+	if settings.readinessGate != nil {
+		expiresAt = expiresAt.Add(settings.readinessGate.maxExtension)
+	}
+
+	delay := now.Sub(expiresAt)
+	if delay < 0 {
+		delay = 0
+	}
+
+	ingressDeletionDelay.Observe(delay.Seconds())
+
+	status.LastIngressDeletion = &networkingv1alpha1.IngressDeletion{
+		Name:      ingress.GetName(),
+		ExpiresAt: metav1.NewTime(expiresAt),
+		DeletedAt: metav1.NewTime(now),
+		Delay:     metav1.Duration{Duration: delay},
+	}
+}
+
+// deferExpiration postpones the given expiration time until the handover preceding it overlaps no blackout window,
+// within the maximum deferral. It also returns the blackout window that deferred it, if any.
+func (s rotationSettings) deferExpiration(expiresAt time.Time) (time.Time, *blackoutPeriod) {
+	if len(s.blackoutWindows) == 0 {
+		return expiresAt, nil
+	}
+
+	handoverStart, deferredBy := deferRotation(s.blackoutWindows, expiresAt.Add(-s.handoverDuration), s.handoverDuration)
+
+	deferredExpiresAt := handoverStart.Add(s.handoverDuration)
+	if s.maxDeferral > 0 && deferredExpiresAt.After(expiresAt.Add(s.maxDeferral)) {
+		deferredExpiresAt = expiresAt.Add(s.maxDeferral)
+	}
+
+	return deferredExpiresAt, deferredBy
+}
+
+// rotationDeferredCondition reports whether the next rotation, the one of the given Ingress, is postponed by a blackout window.
+func (r *RandomIngressReconciler) rotationDeferredCondition(ingress client.Object, settings rotationSettings) networkingv1alpha1.RandomIngressCondition {
+	if ingress != nil {
+		scheduledExpiresAt := ingressScheduledExpiresAt(ingress, settings)
+
+		deferredExpiresAt, deferredBy := settings.deferExpiration(scheduledExpiresAt)
+		if deferredBy != nil {
+			message := fmt.Sprintf("rotation of Ingress %s deferred from %s to %s by blackout window %q",
+				ingress.GetName(), scheduledExpiresAt.UTC().Format(time.RFC3339), deferredExpiresAt.UTC().Format(time.RFC3339), deferredBy.name)
+			if deferredExpiresAt.Before(deferredBy.end.Add(settings.handoverDuration)) {
+				message += fmt.Sprintf(", limited by the maximum deferral of %s", settings.maxDeferral)
+			}
+
+			return r.newCondition(networkingv1alpha1.RandomIngressRotationDeferred, corev1.ConditionTrue, blackoutWindowReason, message)
+		}
+	}
+
+	return r.newCondition(networkingv1alpha1.RandomIngressRotationDeferred, corev1.ConditionFalse, notDeferredReason, "rotation is not deferred")
+}
+
+// observeRotationRequest records a new rotation request in the status of the RandomIngress,
+// and returns the pending rotation request, if any.
+func (r *RandomIngressReconciler) observeRotationRequest(randomIngress *networkingv1alpha1.RandomIngress) *networkingv1alpha1.RotationRequestStatus {
+	request := randomIngress.Spec.RotationRequest
+	if request == nil || request.Token == "" {
+		return nil
+	}
+
+	lastRequest := randomIngress.Status.LastRotationRequest
+	if lastRequest == nil || lastRequest.Token != request.Token {
+		randomIngress.Status.LastRotationRequest = &networkingv1alpha1.RotationRequestStatus{
+			Token:       request.Token,
+			RequestedBy: request.RequestedBy,
+			Strategy:    request.Strategy,
+			RequestedAt: metav1.NewTime(r.Clock.Now()),
+		}
+	}
+
+	return randomIngress.Status.LastRotationRequest
+}
+
+// rotationSettings returns the rotation timings of the given spec,
+// falling back to the operator defaults for the unset fields.
+func (r *RandomIngressReconciler) rotationSettings(spec *networkingv1alpha1.RandomIngressSpec) rotationSettings {
+	settings := rotationSettings{
+		maxLifetime:      r.IngressMaxLifetime,
+		handoverDuration: r.IngressHandoverDuration,
+		maxDeferral:      r.MaxRotationDeferral,
+	}
+
+	settings.blackoutWindows = append(settings.blackoutWindows, r.BlackoutWindows...)
+	settings.blackoutWindows = append(settings.blackoutWindows, spec.BlackoutWindows...)
+
+	if spec.RotationSchedule != nil {
+		// Invalid schedules are reported by validateSpec, and fall back to the max lifetime meanwhile.
+		schedule, err := parseRotationSchedule(spec.RotationSchedule, field.NewPath("spec", "rotationSchedule"))
+		if err == nil && !schedule.Next(r.Clock.Now()).IsZero() {
+			settings.schedule = schedule
+			settings.maxLifetime = r.IngressLifetimeUpperBound
+		}
+	}
+
+	if spec.MaxLifetime != nil {
+		settings.maxLifetime = spec.MaxLifetime.Duration
+	}
+
+	if spec.HandoverDuration != nil {
+		settings.handoverDuration = spec.HandoverDuration.Duration
+	}
+
+	// Invalid lifetimes and schedules along with derived tokens are reported by validateSpec.
+	settings.epochAligned = tokenDerived(spec.Token) && settings.schedule == nil && settings.maxLifetime > 0
+
+	for _, placeholder := range spec.Placeholders {
+		if settings.placeholders == nil {
+			settings.placeholders = map[string]rotationSettings{}
+		}
+
+		placeholderSettings := settings
+		placeholderSettings.placeholders = nil
+		if placeholder.MaxLifetime != nil {
+			placeholderSettings.maxLifetime = placeholder.MaxLifetime.Duration
+		}
+
+		placeholderSettings.epochAligned = tokenDerived(placeholderToken(spec, placeholder.Name)) &&
+			placeholderSettings.schedule == nil && placeholderSettings.maxLifetime > 0
+
+		settings.placeholders[placeholder.Name] = placeholderSettings
+	}
+
+	if spec.ReadinessGate != nil {
+		settings.readinessGate = &readinessGate{
+			matchAddresses: spec.ReadinessGate.MatchAddresses,
+			maxExtension:   r.ReadinessGateMaxExtension,
+		}
+
+		if spec.ReadinessGate.MaxExtension != nil {
+			settings.readinessGate.maxExtension = spec.ReadinessGate.MaxExtension.Duration
+		}
+	}
+
+	return settings
+}
+
+// templateHashes returns the spec hashes of the Ingresses generated from the current template of the spec, the one
+// of new Ingresses first. Ingresses generated from an Ingress template before the spec held rotation settings are
+// named with a legacy hash of the whole spec: they are replaced when they expire, with a handover, like current ones,
+// rather than all at once on upgrade.
+func templateHashes(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	hashes := []string{hash.RandomIngressSpec(spec)}
+	if _, ok := specTarget(spec).(ingressTarget); ok {
+		hashes = append(hashes, hash.LegacyRandomIngressSpec(spec))
+	}
+
+	return hashes
+}
+
+// ingressMatchesSpec returns true if the ingress was generated from the current spec of its RandomIngress.
+// Generated Ingresses are named <RandomIngress name>-<spec hash>-<token hash>. The spec hash only covers the template:
+// token settings, including the Secret derived tokens come from, apply from the next rotation on.
+// Derived tokens, hence the names of the Ingresses, are the same in every cluster sharing the secret.
+func ingressMatchesSpec(ingress client.Object, specHashes []string) bool {
+	nameParts := strings.Split(ingress.GetName(), "-")
 
 	if len(nameParts) < 3 {
 		return false
 	}
 
 	actualSpecHash := nameParts[len(nameParts)-2]
+	for _, specHash := range specHashes {
+		if actualSpecHash == specHash {
+			return true
+		}
+	}
 
-	return actualSpecHash == expectedSpecHash
+	return false
 }
 
-func (r *RandomIngressReconciler) createIngress(randomIngress *networkingv1alpha1.RandomIngress, specHash string) (*networkingv1.Ingress, error) {
-	randomHostpart := r.UUIDSource.NewUUID()
+func (r *RandomIngressReconciler) createIngress(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, specHash string, settings rotationSettings, previous client.Object) (client.Object, error) {
+	issuedAt := r.Clock.Now()
+
+	tokens, err := r.generateTokens(ctx, randomIngress, settings, issuedAt, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenHash := rand.SafeEncodeString(hex.EncodeToString(tokensHash(tokens)))
+	ingressName := fmt.Sprintf("%s-%s-%s", randomIngress.Name, specHash, tokenHash)
+
+	t := specTarget(&randomIngress.Spec)
+	metadata := t.templateMetadata(&randomIngress.Spec)
+
+	// Copy the template annotations and labels, the spec of the RandomIngress must not be modified.
+	annotations := make(map[string]string, len(metadata.Annotations)+3)
+	for key, value := range metadata.Annotations {
+		annotations[key] = replacePlaceholders(value, tokens)
+	}
+
+	var labels map[string]string
+	if metadata.Labels != nil {
+		labels = make(map[string]string, len(metadata.Labels))
+		for key, value := range metadata.Labels {
+			labels[key] = replacePlaceholders(value, tokens)
+		}
+	}
+
+	// The Ingress expires along with its first expiring token.
+	var expiresAt time.Time
+	for _, ingressToken := range tokens {
+		if expiresAt.IsZero() || ingressToken.ExpiresAt.Time.Before(expiresAt) {
+			expiresAt = ingressToken.ExpiresAt.Time
+		}
+	}
 
-	// Needs to be part of the Ingress name to avoid collisions with previous
-	// instances of the Ingress that have the same template.
-	uuidHasher := fnv.New32a()
-	uuidHasher.Write([]byte(randomHostpart))
-	uuidHash := rand.SafeEncodeString(hex.EncodeToString(uuidHasher.Sum(nil)))
-	ingressName := fmt.Sprintf("%s-%s-%s", randomIngress.Name, specHash, uuidHash)
+	encodedTokens, err := json.Marshal(tokens)
+	if err != nil {
+		return nil, err
+	}
 
-	ingressSpec := randomIngress.Spec.IngressTemplate.Spec.DeepCopy()
+	annotations[issuedAtAnnotation] = issuedAt.UTC().Format(time.RFC3339)
+	annotations[expiresAtAnnotation] = expiresAt.UTC().Format(time.RFC3339)
+	annotations[tokensAnnotation] = string(encodedTokens)
 
-	for i := range ingressSpec.Rules {
-		rule := &ingressSpec.Rules[i]
-		rule.Host = strings.ReplaceAll(rule.Host, randomPlaceholder, string(randomHostpart))
+	if settings.rotationRequest != nil {
+		annotations[rotationTokenAnnotation] = settings.rotationRequest.Token
 	}
 
-	result := &networkingv1.Ingress{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:        ingressName,
-			Namespace:   randomIngress.Namespace,
-			Labels:      randomIngress.Spec.IngressTemplate.Metadata.Labels,
-			Annotations: randomIngress.Spec.IngressTemplate.Metadata.Annotations,
-		},
-		Spec: *ingressSpec,
+	result, err := t.render(&randomIngress.Spec, metav1.ObjectMeta{
+		Name:        ingressName,
+		Namespace:   randomIngress.Namespace,
+		Labels:      labels,
+		Annotations: annotations,
+	}, tokens)
+	if err != nil {
+		return nil, err
 	}
 
-	err := ctrl.SetControllerReference(randomIngress, result, r.Scheme)
+	err = ctrl.SetControllerReference(randomIngress, result, r.Scheme)
 	if err != nil {
 		return nil, err
 	}
@@ -255,6 +894,54 @@ func (r *RandomIngressReconciler) createIngress(randomIngress *networkingv1alpha
 	return result, nil
 }
 
+// tokenSource returns the source of the token of the named placeholder for an Ingress issued at the given time:
+// the random token source, or the token derived from the secret, the identity of the RandomIngress,
+// the name of the placeholder, the epoch and the pending rotation request.
+func (r *RandomIngressReconciler) tokenSource(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, name string, settings rotationSettings, issuedAt time.Time) (token.Source, error) {
+	tokenSpec := placeholderToken(&randomIngress.Spec, name)
+	if !tokenDerived(tokenSpec) {
+		return r.TokenSource, nil
+	}
+
+	derivation := tokenSpec.DerivedFrom
+	secretKey := client.ObjectKey{Namespace: randomIngress.Namespace, Name: derivation.SecretKeyRef.Name}
+
+	var secret corev1.Secret
+	if err := r.APIReader.Get(ctx, secretKey, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get token secret %s: %w", secretKey.Name, err)
+	}
+
+	key, ok := secret.Data[derivation.SecretKeyRef.Key]
+	if !ok {
+		return nil, fmt.Errorf("token secret %s has no key %s", secretKey.Name, derivation.SecretKeyRef.Key)
+	}
+
+	if len(key)*8 < r.MinTokenEntropyBits {
+		return nil, fmt.Errorf("key %s of token secret %s must hold at least %d bits",
+			derivation.SecretKeyRef.Key, secretKey.Name, r.MinTokenEntropyBits)
+	}
+
+	identity := derivation.Identity
+	if identity == "" {
+		identity = randomIngress.Namespace + "/" + randomIngress.Name
+	}
+	if name != "" {
+		identity += "#" + name
+	}
+
+	message := fmt.Sprintf("%s\n%d", identity, settings.epoch(issuedAt))
+	if settings.rotationRequest != nil {
+		message += "\n" + settings.rotationRequest.Token
+	}
+
+	return token.DerivedSource{Key: key, Message: []byte(message)}, nil
+}
+
+// tokenDerived returns true if the tokens of the given spec are derived from a secret.
+func tokenDerived(spec *networkingv1alpha1.TokenSpec) bool {
+	return spec != nil && spec.DerivedFrom != nil
+}
+
 // TODO: simplify this. See if we can get rid of pointer type.
 func getCondition(status networkingv1alpha1.RandomIngressStatus, condType networkingv1alpha1.RandomIngressConditionType) *networkingv1alpha1.RandomIngressCondition {
 	for _, cond := range status.Conditions {
@@ -288,6 +975,62 @@ func setCondition(status *networkingv1alpha1.RandomIngressStatus, condition netw
 	status.Conditions = append(status.Conditions, condition)
 }
 
+// setActiveIngresses reports the given live Ingresses in the status, newest first.
+func (r *RandomIngressReconciler) setActiveIngresses(status *networkingv1alpha1.RandomIngressStatus, liveIngresses []client.Object, settings rotationSettings) {
+	sort.SliceStable(liveIngresses, func(i, j int) bool {
+		return ingressIssuedAt(liveIngresses[i]).After(ingressIssuedAt(liveIngresses[j]))
+	})
+
+	status.ActiveIngresses = nil
+	status.CurrentHosts = nil
+
+	for i, ingress := range liveIngresses {
+		var phase networkingv1alpha1.ActiveIngressPhase
+		switch {
+		case i > 0, r.ingressExpiringSoon(ingress, settings):
+			phase = networkingv1alpha1.ActiveIngressExpiring
+		case len(liveIngresses) > 1:
+			phase = networkingv1alpha1.ActiveIngressHandover
+		default:
+			phase = networkingv1alpha1.ActiveIngressActive
+		}
+
+		status.ActiveIngresses = append(status.ActiveIngresses, networkingv1alpha1.ActiveIngress{
+			Name:      ingress.GetName(),
+			Hosts:     objectTarget(ingress).hosts(ingress),
+			CreatedAt: metav1.NewTime(ingressIssuedAt(ingress)),
+			ExpiresAt: metav1.NewTime(ingressExpiresAt(ingress, settings)),
+			Phase:     phase,
+		})
+	}
+
+	status.Tokens = nil
+	if len(status.ActiveIngresses) > 0 {
+		status.CurrentHosts = status.ActiveIngresses[0].Hosts
+		status.Tokens = tokensStatus(liveIngresses[0], settings)
+	}
+}
+
+// progressingCondition reports whether several generations of Ingress currently overlap,
+// given the number of live Ingresses at the end of the reconciliation and the changes it made.
+func (r *RandomIngressReconciler) progressingCondition(liveIngresses int, newIngress client.Object, deletedIngresses int) networkingv1alpha1.RandomIngressCondition {
+	switch {
+	case liveIngresses > 1 && newIngress != nil:
+		message := fmt.Sprintf("created Ingress %s, %d Ingresses are live", newIngress.GetName(), liveIngresses)
+		return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionTrue, newIngressCreatedReason, message)
+	case liveIngresses > 1 && deletedIngresses > 0:
+		message := fmt.Sprintf("deleted %d expired Ingresses, %d Ingresses are live", deletedIngresses, liveIngresses)
+		return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionTrue, oldIngressDeletedReason, message)
+	case liveIngresses > 1:
+		message := fmt.Sprintf("%d Ingresses are live", liveIngresses)
+		return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionTrue, handoverInProgressReason, message)
+	case liveIngresses == 1:
+		return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionFalse, singleIngressLiveReason, "a single Ingress is live")
+	default:
+		return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionFalse, noIngressLiveReason, "no Ingress is live")
+	}
+}
+
 func (r *RandomIngressReconciler) newCondition(condType networkingv1alpha1.RandomIngressConditionType, status corev1.ConditionStatus, reason, message string) networkingv1alpha1.RandomIngressCondition {
 	now := metav1.NewTime(r.Clock.Now())
 
@@ -301,16 +1044,82 @@ func (r *RandomIngressReconciler) newCondition(condType networkingv1alpha1.Rando
 	}
 }
 
-// ingressExpired returns true if the input ingress has passed the maximum lifetime.
-func (r *RandomIngressReconciler) ingressExpired(ingress *networkingv1.Ingress) bool {
-	oldestAcceptableCreation := r.Clock.Now().Add(-r.IngressMaxLifetime)
-	return ingress.CreationTimestamp.Time.Before(oldestAcceptableCreation)
+// ingressExpired returns true if the input ingress has reached its expiration time.
+func (r *RandomIngressReconciler) ingressExpired(ingress client.Object, settings rotationSettings) bool {
+	return !r.Clock.Now().Before(ingressExpiresAt(ingress, settings))
+}
+
+// ingressExpiringSoon returns true if the input ingress is within the handover duration of its expiration.
+func (r *RandomIngressReconciler) ingressExpiringSoon(ingress client.Object, settings rotationSettings) bool {
+	return !r.Clock.Now().Before(ingressExpiresAt(ingress, settings).Add(-settings.handoverDuration))
+}
+
+// ingressIssuedAt returns the time at which the ingress was issued.
+// Ingresses created before the issued-at annotation existed fall back to their creation timestamp.
+func ingressIssuedAt(ingress client.Object) time.Time {
+	if issuedAt, ok := timeAnnotation(ingress, issuedAtAnnotation); ok {
+		return issuedAt
+	}
+
+	return ingress.GetCreationTimestamp().Time
 }
 
-// ingressExpiringSoon returns true if the input ingress is within IngressHandoverDuration of its expiration.
-func (r *RandomIngressReconciler) ingressExpiringSoon(ingress *networkingv1.Ingress) bool {
-	oldestAcceptableCreation := r.Clock.Now().Add(-r.IngressMaxLifetime).Add(r.IngressHandoverDuration)
-	return ingress.CreationTimestamp.Time.Before(oldestAcceptableCreation)
+// ingressScheduledExpiresAt returns the time at which the ingress is scheduled to expire: the expiration stamped
+// at creation, or earlier if the lifetime or schedule of the RandomIngress has been changed since.
+// Ingresses recording their tokens expire along with the first of them.
+func ingressScheduledExpiresAt(ingress client.Object, settings rotationSettings) time.Time {
+	if tokens, ok := ingressTokens(ingress); ok {
+		var expiresAt time.Time
+		for name, ingressToken := range tokens {
+			if tokenExpiresAt := tokenScheduledExpiresAt(ingressToken, settings.forPlaceholder(name)); expiresAt.IsZero() || tokenExpiresAt.Before(expiresAt) {
+				expiresAt = tokenExpiresAt
+			}
+		}
+
+		return expiresAt
+	}
+
+	expiresAt := settings.expiresAt(ingressIssuedAt(ingress))
+
+	if stampedExpiresAt, ok := timeAnnotation(ingress, expiresAtAnnotation); ok && stampedExpiresAt.Before(expiresAt) {
+		expiresAt = stampedExpiresAt
+	}
+
+	return expiresAt
+}
+
+// ingressExpiresAt returns the time at which the ingress expires: its scheduled expiration postponed by blackout windows,
+// or earlier if a rotation was requested since.
+func ingressExpiresAt(ingress client.Object, settings rotationSettings) time.Time {
+	expiresAt, _ := settings.deferExpiration(ingressScheduledExpiresAt(ingress, settings))
+
+	if request := settings.rotationRequest; request != nil && ingress.GetAnnotations()[rotationTokenAnnotation] != request.Token {
+		rotationDeadline := request.RequestedAt.Time
+		if request.Strategy != networkingv1alpha1.RotationStrategyImmediate {
+			rotationDeadline = rotationDeadline.Add(settings.handoverDuration)
+		}
+
+		if rotationDeadline.Before(expiresAt) {
+			expiresAt = rotationDeadline
+		}
+	}
+
+	return expiresAt
+}
+
+// timeAnnotation parses the RFC 3339 time stored in the given annotation of the ingress.
+func timeAnnotation(ingress client.Object, key string) (time.Time, bool) {
+	value, ok := ingress.GetAnnotations()[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return parsed, true
 }
 
 const ingressOwnerKey = ".metadata.controller"
@@ -321,14 +1130,17 @@ func (r *RandomIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		r.Clock = realClock{}
 	}
 
-	if r.UUIDSource == nil {
-		r.UUIDSource = realUUIDSource{}
+	if r.TokenSource == nil {
+		r.TokenSource = token.CryptoSource{}
+	}
+
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
 	}
 
 	var ourAPIVersion = networkingv1alpha1.GroupVersion.String()
 
-	// Setup a memory index on Ingress objects, keyed by the owning RandomIngress, so we can easily query them when reconciling.
-	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &networkingv1.Ingress{}, ingressOwnerKey, func(obj client.Object) []string {
+	indexOwner := func(obj client.Object) []string {
 		owner := metav1.GetControllerOf(obj)
 		if owner == nil {
 			return nil
@@ -341,12 +1153,30 @@ func (r *RandomIngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 		// And if so, return its name so the Ingress is indexed by its controlling RandomIngress
 		return []string{owner.Name}
-	}); err != nil {
-		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&networkingv1alpha1.RandomIngress{}).
-		Owns(&networkingv1.Ingress{}).
-		Complete(r)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1alpha1.RandomIngress{})
+
+	for _, t := range r.targets() {
+		t := t
+
+		// Setup a memory index on the generated objects, keyed by the owning RandomIngress, so we can easily query them when reconciling.
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), t.newObject(), ingressOwnerKey, indexOwner); err != nil {
+			return err
+		}
+
+		if r.DetectHostConflicts {
+			// Index all objects of the kind by host as well, to find the ones serving the hosts of a RandomIngress.
+			if err := mgr.GetFieldIndexer().IndexField(context.Background(), t.newObject(), ingressHostKey, func(obj client.Object) []string {
+				return t.hosts(obj)
+			}); err != nil {
+				return err
+			}
+		}
+
+		builder = builder.Owns(t.newObject())
+	}
+
+	return builder.Complete(r)
 }
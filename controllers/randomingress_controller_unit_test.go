@@ -7,6 +7,8 @@ package controllers
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -16,10 +18,14 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,6 +34,7 @@ import (
 	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
 	mock_client "github.com/BackMarket-oss/random-ingress-operator/controllers/mocks"
 	"github.com/BackMarket-oss/random-ingress-operator/controllers/testutils"
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/hash"
 )
 
 const testMaxLifetime = 2 * time.Minute
@@ -108,13 +115,13 @@ func TestRandomIngressReconciler_InvalidSpec(t *testing.T) {
 				FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 			}
 
-			testUUIDSource := testutils.NewFakeUUIDSource(t, []types.UID{})
+			testTokenSource := testutils.NewFakeTokenSource(t, []string{})
 
 			reconciler := RandomIngressReconciler{
 				Client:                  testClient,
 				Scheme:                  scheme.Scheme,
 				Clock:                   clock,
-				UUIDSource:              testUUIDSource,
+				TokenSource:             testTokenSource,
 				IngressMaxLifetime:      testMaxLifetime,
 				IngressHandoverDuration: testGracePeriod,
 			}
@@ -139,7 +146,65 @@ func TestRandomIngressReconciler_InvalidSpec(t *testing.T) {
 						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
 						Message:            tc.expectedMessage,
 					},
+					{
+						Type:               networkingv1alpha1.RandomIngressProgressing,
+						Status:             corev1.ConditionFalse,
+						Reason:             noIngressLiveReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no Ingress is live",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressSuspended,
+						Status:             corev1.ConditionFalse,
+						Reason:             rotationActiveReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "rotation is active",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressRotationDeferred,
+						Status:             corev1.ConditionFalse,
+						Reason:             notDeferredReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "rotation is not deferred",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressReplacementFailed,
+						Status:             corev1.ConditionFalse,
+						Reason:             noReplacementFailureReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no replacement failed",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressPerHostCertificateRequired,
+						Status:             corev1.ConditionFalse,
+						Reason:             noRandomTLSHostsReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no TLS host needs a per-host certificate",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressHostConflict,
+						Status:             corev1.ConditionFalse,
+						Reason:             hostConflictDetectionOffReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "host conflict detection is disabled",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressNewIngressNotReady,
+						Status:             corev1.ConditionFalse,
+						Reason:             readinessGateDisabledReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no readiness gate is configured",
+					},
 				},
+
+				TokenEntropyBits: 122,
 			}
 
 			assertStatusEquivalent(t, expectedStatus, actualStatus)
@@ -157,11 +222,11 @@ func TestRandomIngressReconciler_NoExistingIngress(t *testing.T) {
 		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	returnedUUIDs := []types.UID{
-		types.UID("6900d1a3-798c-4d9a-9a2f-737c72046efa"),
+	returnedTokens := []string{
+		"6900d1a3-798c-4d9a-9a2f-737c72046efa",
 	}
 
-	testUUIDSource := testutils.NewFakeUUIDSource(t, returnedUUIDs)
+	testTokenSource := testutils.NewFakeTokenSource(t, returnedTokens)
 
 	nextRenewalTime := clock.FixedNow.Add(testMaxLifetime)
 
@@ -182,7 +247,7 @@ func TestRandomIngressReconciler_NoExistingIngress(t *testing.T) {
 		Client:                  testClient,
 		Scheme:                  scheme.Scheme,
 		Clock:                   clock,
-		UUIDSource:              testUUIDSource,
+		TokenSource:             testTokenSource,
 		IngressMaxLifetime:      testMaxLifetime,
 		IngressHandoverDuration: testGracePeriod,
 	}
@@ -192,10 +257,18 @@ func TestRandomIngressReconciler_NoExistingIngress(t *testing.T) {
 	assert.NotNil(t, res)
 	assert.Equal(t, testMaxLifetime-testGracePeriod, res.RequeueAfter)
 
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(actualIngress, clock.FixedNow, nextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
 	assertStatusEquivalent(t, expectedStatus, actualStatus)
-	assert.Len(t, testUUIDSource.Items, 0)
+	assert.Len(t, testTokenSource.Items, 0)
+
+	assertIngressMatchesTemplate(t, randomIngress, actualIngress, returnedTokens[0])
+	assert.Equal(t, "2021-09-06T17:12:00Z", actualIngress.Annotations[issuedAtAnnotation])
+	assert.Equal(t, "2021-09-06T17:14:00Z", actualIngress.Annotations[expiresAtAnnotation])
 
-	assertIngressMatchesTemplate(t, randomIngress, actualIngress, returnedUUIDs[0])
+	// The template must not be modified by the annotations added to the Ingress.
+	assert.Equal(t, testutils.ValidRandomIng.Spec.IngressTemplate.Metadata.Annotations, randomIngress.Spec.IngressTemplate.Metadata.Annotations)
 }
 
 func TestRandomIngressReconciler_AlreadyLiveIngress(t *testing.T) {
@@ -207,7 +280,7 @@ func TestRandomIngressReconciler_AlreadyLiveIngress(t *testing.T) {
 		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	testUUIDSource := testutils.NewFakeUUIDSource(t, []types.UID{})
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{})
 
 	testClient, statusClient := newClientMock(ctrl)
 	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
@@ -235,7 +308,7 @@ func TestRandomIngressReconciler_AlreadyLiveIngress(t *testing.T) {
 		Client:                  testClient,
 		Scheme:                  scheme.Scheme,
 		Clock:                   clock,
-		UUIDSource:              testUUIDSource,
+		TokenSource:             testTokenSource,
 		IngressMaxLifetime:      testMaxLifetime,
 		IngressHandoverDuration: testGracePeriod,
 	}
@@ -245,6 +318,9 @@ func TestRandomIngressReconciler_AlreadyLiveIngress(t *testing.T) {
 	assert.NotNil(t, res)
 	assert.Equal(t, expectedRequeueAfter, res.RequeueAfter)
 
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(existingIngress, existingCreationTimestamp, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
 	assertStatusEquivalent(t, expectedStatus, actualStatus)
 }
 
@@ -256,7 +332,7 @@ func TestRandomIngressReconciler_SpecChanged(t *testing.T) {
 		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	testUUIDSource := testutils.NewFakeUUIDSource(t, []types.UID{
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{
 		"0d46c579-055a-42dd-b3c9-5a2418eeb44c",
 	})
 
@@ -295,9 +371,9 @@ func TestRandomIngressReconciler_SpecChanged(t *testing.T) {
 	gomock.InOrder(
 		expectGetRandomIngress(testClient, randomIngress, nil),
 		expectListIngresses(testClient, "default", "randomIngress", existingIngresses, nil),
+		createIngressCall,
 		expectDeleteIngress(testClient, existingIngressWrongSpec, nil),
 		expectDeleteIngress(testClient, existingIngressWrongName, nil),
-		createIngressCall,
 		updateStatusCall,
 	)
 
@@ -305,7 +381,7 @@ func TestRandomIngressReconciler_SpecChanged(t *testing.T) {
 		Client:                  testClient,
 		Scheme:                  scheme.Scheme,
 		Clock:                   clock,
-		UUIDSource:              testUUIDSource,
+		TokenSource:             testTokenSource,
 		IngressMaxLifetime:      testMaxLifetime,
 		IngressHandoverDuration: testGracePeriod,
 	}
@@ -316,6 +392,9 @@ func TestRandomIngressReconciler_SpecChanged(t *testing.T) {
 	assert.Equal(t, expectedRequeueAfter, res.RequeueAfter)
 	assert.NotNil(t, actualIngress)
 
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(actualIngress, clock.FixedNow, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
 	assertStatusEquivalent(t, expectedStatus, actualStatus)
 }
 
@@ -328,8 +407,8 @@ func TestRandomIngressReconciler_ExpiredIngress(t *testing.T) {
 		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	expectedIngressUID := types.UID("669f0808-4f8a-4aa5-a231-c7f7d313bfbe")
-	testUUIDSource := testutils.NewFakeUUIDSource(t, []types.UID{expectedIngressUID})
+	expectedToken := "669f0808-4f8a-4aa5-a231-c7f7d313bfbe"
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
 
 	// Expired by 1 second
 	existingCreationTimestamp := clock.FixedNow.Add(-testMaxLifetime).Add(-time.Second)
@@ -355,8 +434,8 @@ func TestRandomIngressReconciler_ExpiredIngress(t *testing.T) {
 	gomock.InOrder(
 		expectGetRandomIngress(testClient, randomIngress, nil),
 		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
-		expectDeleteIngress(testClient, existingIngress, nil),
 		createIngressCall,
+		expectDeleteIngress(testClient, existingIngress, nil),
 		updateStatusCall,
 	)
 
@@ -364,7 +443,7 @@ func TestRandomIngressReconciler_ExpiredIngress(t *testing.T) {
 		Client:                  testClient,
 		Scheme:                  scheme.Scheme,
 		Clock:                   clock,
-		UUIDSource:              testUUIDSource,
+		TokenSource:             testTokenSource,
 		IngressMaxLifetime:      testMaxLifetime,
 		IngressHandoverDuration: testGracePeriod,
 	}
@@ -374,122 +453,3860 @@ func TestRandomIngressReconciler_ExpiredIngress(t *testing.T) {
 	assert.NotNil(t, res)
 	assert.Equal(t, expectedRequeueAfter, res.RequeueAfter)
 
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(actualIngress, clock.FixedNow, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+	expectedStatus.LastIngressDeletion = newExpectedIngressDeletion(existingIngress, clock.FixedNow.Add(-time.Second), clock.FixedNow)
+
 	assertStatusEquivalent(t, expectedStatus, actualStatus)
-	assertIngressMatchesTemplate(t, &testutils.ValidRandomIng, actualIngress, expectedIngressUID)
+	assertIngressMatchesTemplate(t, &testutils.ValidRandomIng, actualIngress, expectedToken)
 }
 
-func newReq(namespace, name string) reconcile.Request {
-	return reconcile.Request{
-		NamespacedName: types.NamespacedName{
-			Namespace: namespace,
-			Name:      name,
-		},
+func TestRandomIngressReconciler_LegacySpecHash(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
-}
 
-// newClientMock creates a mock of Client with the Status sub-client also created, and returns both.
-func newClientMock(ctrl *gomock.Controller) (*mock_client.MockClient, *mock_client.MockStatusWriter) {
-	statusClient := mock_client.NewMockStatusWriter(ctrl)
-	client := mock_client.NewMockClient(ctrl)
-	client.EXPECT().Status().AnyTimes().Return(statusClient)
+	// Ingresses generated before the spec held rotation settings are named with a hash of the whole spec.
+	legacyIngress := func(createdAgo time.Duration) *networkingv1.Ingress {
+		ingress := testutils.ValidIngress.DeepCopy()
+		ingress.Name = "randomIngress-bxw44w6b-123abc45"
+		ingress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-createdAgo))
+		return ingress
+	}
 
-	return client, statusClient
+	t.Run("legacy Ingress kept until its handover", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, &testutils.ValidRandomIng, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{legacyIngress(time.Minute)}, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Equal(t, testMaxLifetime-testGracePeriod-time.Minute, res.RequeueAfter)
+
+		if assert.Len(t, actualStatus.ActiveIngresses, 1) {
+			assert.Equal(t, networkingv1alpha1.ActiveIngressActive, actualStatus.ActiveIngresses[0].Phase)
+		}
+	})
+
+	t.Run("legacy Ingress replaced with a handover", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		// The legacy Ingress is not deleted before its expiration.
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, &testutils.ValidRandomIng, nil),
+			expectListIngresses(testClient, "default", "randomIngress",
+				[]*networkingv1.Ingress{legacyIngress(testMaxLifetime - testGracePeriod/2)}, nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{"af2b1e34-5b6e-4b2e-9a55-2f3b2cb0a2f4"}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Equal(t, testGracePeriod/2, res.RequeueAfter)
+
+		assert.Contains(t, actualIngress.Name, hash.RandomIngressSpec(&testutils.ValidRandomIng.Spec))
+		assert.Len(t, actualStatus.ActiveIngresses, 2)
+	})
 }
 
-func expectGetRandomIngress(mock *mock_client.MockClient, expectedOutput *networkingv1alpha1.RandomIngress, expectedErr error) *gomock.Call {
-	key := client.ObjectKey{
-		Namespace: expectedOutput.Namespace,
-		Name:      expectedOutput.Name,
+func TestRandomIngressReconciler_Handover(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	call := mock.EXPECT().Get(gomock.Not(gomock.Nil()), key, gomock.AssignableToTypeOf(expectedOutput)).
-		DoAndReturn(func(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...interface{}) error {
-			if expectedOutput != nil {
-				outObj := obj.(*networkingv1alpha1.RandomIngress)
-				expectedOutput.DeepCopyInto(outObj)
-			}
-			return expectedErr
+	// The old Ingress is in the middle of its handover period.
+	oldIngress := testutils.ValidIngress.DeepCopy()
+	oldIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(testGracePeriod / 2))
+
+	t.Run("new Ingress created", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedToken := "af2b1e34-5b6e-4b2e-9a55-2f3b2cb0a2f4"
+		testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{oldIngress}, nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testTokenSource,
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		// Requeue to delete the old Ingress right when it expires, rather than at the handover of the new one.
+		assert.Equal(t, testGracePeriod/2, res.RequeueAfter)
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow.Add(testMaxLifetime))
+		setExpectedCondition(expectedStatus, networkingv1alpha1.RandomIngressCondition{
+			Type:               networkingv1alpha1.RandomIngressProgressing,
+			Status:             corev1.ConditionTrue,
+			Reason:             newIngressCreatedReason,
+			Message:            fmt.Sprintf("created Ingress %s, 2 Ingresses are live", actualIngress.Name),
+			LastHeartbeatTime:  metav1.NewTime(clock.FixedNow),
+			LastTransitionTime: metav1.NewTime(clock.FixedNow),
 		})
 
-	return call
-}
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(actualIngress, clock.FixedNow, clock.FixedNow.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressHandover),
+			newExpectedActiveIngress(oldIngress, oldIngress.CreationTimestamp.Time, oldIngress.CreationTimestamp.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressExpiring))
 
-func expectListIngresses(mock *mock_client.MockClient, namespace, ownerName string, expectedItems []*networkingv1.Ingress, expectedErr error) *gomock.Call {
-	var list *networkingv1.IngressList
-	call := mock.EXPECT().List(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(list), client.InNamespace(namespace), client.MatchingFields{ingressOwnerKey: ownerName}).
-		DoAndReturn(func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
-			outObj := list.(*networkingv1.IngressList)
-			for _, item := range expectedItems {
-				outObj.Items = append(outObj.Items, *item)
-			}
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+		assertIngressMatchesTemplate(t, randomIngress, actualIngress, expectedToken)
+	})
 
-			return expectedErr
+	t.Run("both Ingresses live", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		newIngress := testutils.ValidIngress.DeepCopy()
+		newIngress.Name = fmt.Sprintf("%s-%s-987fed65", randomIngress.Name, hash.RandomIngressSpec(&randomIngress.Spec))
+		newIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-time.Second))
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{oldIngress, newIngress}, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Equal(t, testGracePeriod/2, res.RequeueAfter)
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, newIngress.CreationTimestamp.Add(testMaxLifetime))
+		setExpectedCondition(expectedStatus, networkingv1alpha1.RandomIngressCondition{
+			Type:               networkingv1alpha1.RandomIngressProgressing,
+			Status:             corev1.ConditionTrue,
+			Reason:             handoverInProgressReason,
+			Message:            "2 Ingresses are live",
+			LastHeartbeatTime:  metav1.NewTime(clock.FixedNow),
+			LastTransitionTime: metav1.NewTime(clock.FixedNow),
 		})
 
-	return call
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(newIngress, newIngress.CreationTimestamp.Time, newIngress.CreationTimestamp.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressHandover),
+			newExpectedActiveIngress(oldIngress, oldIngress.CreationTimestamp.Time, oldIngress.CreationTimestamp.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressExpiring))
+
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+	})
 }
 
-func expectUpdateStatus(mock *mock_client.MockStatusWriter, expectedErr error) (*gomock.Call, *networkingv1alpha1.RandomIngressStatus) {
-	var r *networkingv1alpha1.RandomIngress
+func TestRandomIngressReconciler_RotationRequest(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
 
-	result := networkingv1alpha1.RandomIngressStatus{}
-	call := mock.EXPECT().Update(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(r))).
-		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
-			ingress := obj.(*networkingv1alpha1.RandomIngress)
-			ingress.Status.DeepCopyInto(&result)
+	// The current Ingress is in the middle of its lifetime, and has leaked.
+	existingCreationTimestamp := clock.FixedNow.Add(-testMaxLifetime / 2)
+	existingIngress := testutils.ValidIngress.DeepCopy()
+	existingIngress.CreationTimestamp = metav1.NewTime(existingCreationTimestamp)
 
-			return expectedErr
+	expectedRequest := &networkingv1alpha1.RotationRequestStatus{
+		Token:       "leaked-in-ticket-1234",
+		RequestedBy: "jane.doe",
+		RequestedAt: metav1.NewTime(clock.FixedNow),
+	}
+
+	t.Run("handover", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.RotationRequest = &networkingv1alpha1.RotationRequest{
+			Token:       "leaked-in-ticket-1234",
+			RequestedBy: "jane.doe",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedToken := "0b0e1d7c-0d2f-4f55-a0b3-3a1e4b4c1c59"
+		testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testTokenSource,
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		assertIngressMatchesTemplate(t, randomIngress, actualIngress, expectedToken)
+		assert.Equal(t, "leaked-in-ticket-1234", actualIngress.Annotations[rotationTokenAnnotation])
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow.Add(testMaxLifetime))
+		expectedStatus.LastRotationRequest = expectedRequest
+		setExpectedCondition(expectedStatus, networkingv1alpha1.RandomIngressCondition{
+			Type:               networkingv1alpha1.RandomIngressProgressing,
+			Status:             corev1.ConditionTrue,
+			Reason:             newIngressCreatedReason,
+			Message:            fmt.Sprintf("created Ingress %s, 2 Ingresses are live", actualIngress.Name),
+			LastHeartbeatTime:  metav1.NewTime(clock.FixedNow),
+			LastTransitionTime: metav1.NewTime(clock.FixedNow),
 		})
 
-	return call, &result
+		// The leaked Ingress only survives for the handover duration.
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(actualIngress, clock.FixedNow, clock.FixedNow.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressHandover),
+			newExpectedActiveIngress(existingIngress, existingCreationTimestamp, clock.FixedNow.Add(testGracePeriod), networkingv1alpha1.ActiveIngressExpiring))
+
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+	})
+
+	t.Run("immediate", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.RotationRequest = &networkingv1alpha1.RotationRequest{
+			Token:       "leaked-in-ticket-1234",
+			RequestedBy: "jane.doe",
+			Strategy:    networkingv1alpha1.RotationStrategyImmediate,
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedToken := "7d8b5a8e-2c55-4f7e-9c3b-8d1f0e6a4b21"
+		testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+			createIngressCall,
+			expectDeleteIngress(testClient, existingIngress, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testTokenSource,
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow.Add(testMaxLifetime))
+		expectedStatus.LastRotationRequest = expectedRequest.DeepCopy()
+		expectedStatus.LastRotationRequest.Strategy = networkingv1alpha1.RotationStrategyImmediate
+		expectedStatus.LastIngressDeletion = newExpectedIngressDeletion(existingIngress, clock.FixedNow, clock.FixedNow)
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(actualIngress, clock.FixedNow, clock.FixedNow.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressActive))
+
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+	})
+
+	t.Run("already rotated", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.RotationRequest = &networkingv1alpha1.RotationRequest{
+			Token:       "leaked-in-ticket-1234",
+			RequestedBy: "jane.doe",
+		}
+		randomIngress.Status.LastRotationRequest = &networkingv1alpha1.RotationRequestStatus{
+			Token:       "leaked-in-ticket-1234",
+			RequestedBy: "jane.doe",
+			RequestedAt: metav1.NewTime(existingCreationTimestamp),
+		}
+
+		rotatedIngress := existingIngress.DeepCopy()
+		rotatedIngress.Annotations = map[string]string{
+			rotationTokenAnnotation: "leaked-in-ticket-1234",
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{rotatedIngress}, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		expectedNextRenewalTime := existingCreationTimestamp.Add(testMaxLifetime)
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, expectedNextRenewalTime)
+		expectedStatus.LastRotationRequest = randomIngress.Status.LastRotationRequest
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(rotatedIngress, existingCreationTimestamp, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+	})
 }
 
-func expectCreateIngress(mock *mock_client.MockClient, expectedErr error) (*gomock.Call, *networkingv1.Ingress) {
-	result := &networkingv1.Ingress{}
+func TestRandomIngressReconciler_Suspended(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
 
-	call := mock.EXPECT().Create(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(result))).
-		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
-			ingress := obj.(*networkingv1.Ingress)
-			ingress.DeepCopyInto(result)
+	// Expired by 1 second
+	existingCreationTimestamp := clock.FixedNow.Add(-testMaxLifetime).Add(-time.Second)
+	existingIngress := testutils.ValidIngress.DeepCopy()
+	existingIngress.CreationTimestamp = metav1.NewTime(existingCreationTimestamp)
 
-			return expectedErr
+	t.Run("suspended until a deadline", func(t *testing.T) {
+		suspendUntil := metav1.NewTime(clock.FixedNow.Add(time.Hour))
+
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.Suspend = true
+		randomIngress.Spec.SuspendUntil = &suspendUntil
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		// The expired Ingress must be neither deleted nor replaced.
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour, res.RequeueAfter)
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow)
+		expectedStatus.NextRenewalTime = nil
+		setExpectedCondition(expectedStatus, networkingv1alpha1.RandomIngressCondition{
+			Type:               networkingv1alpha1.RandomIngressSuspended,
+			Status:             corev1.ConditionTrue,
+			Reason:             rotationSuspendedReason,
+			Message:            "rotation is suspended until 2021-09-06T18:12:00Z",
+			LastHeartbeatTime:  metav1.NewTime(clock.FixedNow),
+			LastTransitionTime: metav1.NewTime(clock.FixedNow),
 		})
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(existingIngress, existingCreationTimestamp, existingCreationTimestamp.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressExpiring))
 
-	return call, result
-}
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+	})
 
-func expectDeleteIngress(mock *mock_client.MockClient, expectedIn *networkingv1.Ingress, expectedErr error) *gomock.Call {
-	call := mock.EXPECT().Delete(gomock.Not(gomock.Nil()), gomock.Eq(expectedIn)).
-		Return(expectedErr)
+	t.Run("suspension over", func(t *testing.T) {
+		suspendUntil := metav1.NewTime(clock.FixedNow.Add(-time.Minute))
 
-	return call
-}
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.Suspend = true
+		randomIngress.Spec.SuspendUntil = &suspendUntil
 
-func assertStatusEquivalent(t *testing.T, expected, actual *networkingv1alpha1.RandomIngressStatus) {
-	if expected == nil || actual == nil {
-		if !assert.Equal(t, expected, actual) {
-			return
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedToken := "5f7c9a1e-52a4-4a3c-bd43-4c1b7c3e9f10"
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+			createIngressCall,
+			expectDeleteIngress(testClient, existingIngress, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{expectedToken}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
 		}
+
+		res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Equal(t, testMaxLifetime-testGracePeriod, res.RequeueAfter)
+
+		expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow.Add(testMaxLifetime))
+		expectedStatus.LastIngressDeletion = newExpectedIngressDeletion(existingIngress, clock.FixedNow.Add(-time.Second), clock.FixedNow)
+		setExpectedActiveIngresses(expectedStatus,
+			newExpectedActiveIngress(actualIngress, clock.FixedNow, clock.FixedNow.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressActive))
+
+		assertStatusEquivalent(t, expectedStatus, actualStatus)
+		assertIngressMatchesTemplate(t, randomIngress, actualIngress, expectedToken)
+	})
+}
+
+func TestRandomIngressReconciler_RotationSchedule(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.RotationSchedule = &networkingv1alpha1.RotationSchedule{
+		Cron:     "0 3 * * *",
+		TimeZone: "Europe/Paris",
 	}
 
-	conditionsSorter := func(conds []networkingv1alpha1.RandomIngressCondition) func(i, j int) bool {
-		return func(i, j int) bool {
-			return conds[i].Type < conds[j].Type
-		}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
 	}
 
-	sort.SliceStable(expected.Conditions, conditionsSorter(expected.Conditions))
-	sort.SliceStable(actual.Conditions, conditionsSorter(actual.Conditions))
+	expectedToken := "e0c2b8a4-6f1d-4b8e-a3c7-9d2f5e1b7a60"
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
 
-	assert.Equal(t, expected, actual)
-}
+	// 03:00 in Paris is 01:00 UTC during summer time.
+	expectedNextRenewalTime := time.Date(2021, time.September, 07, 1, 0, 0, 0, time.UTC)
 
-func assertIngressMatchesTemplate(t *testing.T, template *networkingv1alpha1.RandomIngress, actual *networkingv1.Ingress, randomId types.UID) {
-	assert.NotNil(t, template)
-	assert.NotNil(t, actual)
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
 
-	assert.Equal(t, template.Namespace, actual.Namespace)
-	assert.Equal(t, template.Spec.IngressTemplate.Metadata.Annotations, actual.Annotations)
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+		createIngressCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testTokenSource,
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	// The handover starts before the tick of the schedule.
+	assert.Equal(t, expectedNextRenewalTime.Sub(clock.FixedNow)-testGracePeriod, res.RequeueAfter)
+
+	expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, expectedNextRenewalTime)
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(actualIngress, clock.FixedNow, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
+	assertStatusEquivalent(t, expectedStatus, actualStatus)
+	assert.Equal(t, "2021-09-07T01:00:00Z", actualIngress.Annotations[expiresAtAnnotation])
+}
+
+func TestRotationSettings_ExpiresAt(t *testing.T) {
+	schedule, err := parseRotationSchedule(&networkingv1alpha1.RotationSchedule{Cron: "0 3 * * *"}, nil)
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name     string
+		settings rotationSettings
+		issuedAt time.Time
+		expected time.Time
+	}{
+		{
+			name:     "max lifetime",
+			settings: rotationSettings{maxLifetime: time.Hour, handoverDuration: time.Minute},
+			issuedAt: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+			expected: time.Date(2021, time.September, 06, 18, 12, 0, 0, time.UTC),
+		},
+		{
+			name:     "next tick",
+			settings: rotationSettings{handoverDuration: 10 * time.Minute, schedule: schedule},
+			issuedAt: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+			expected: time.Date(2021, time.September, 07, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "issued during the handover of a tick",
+			settings: rotationSettings{handoverDuration: 10 * time.Minute, schedule: schedule},
+			issuedAt: time.Date(2021, time.September, 06, 2, 52, 0, 0, time.UTC),
+			expected: time.Date(2021, time.September, 07, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "tick capped by max lifetime",
+			settings: rotationSettings{maxLifetime: time.Hour, handoverDuration: 10 * time.Minute, schedule: schedule},
+			issuedAt: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+			expected: time.Date(2021, time.September, 06, 18, 12, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.settings.expiresAt(tc.issuedAt))
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ValidateRotationSchedule(t *testing.T) {
+	testCases := []struct {
+		name             string
+		rotationSchedule networkingv1alpha1.RotationSchedule
+		expectedMessage  string
+	}{
+		{
+			name:             "valid",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "0 3 * * 1-5", TimeZone: "Europe/Paris"},
+		},
+		{
+			name:             "unknown time zone",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "0 3 * * *", TimeZone: "Europe/Atlantis"},
+			expectedMessage:  `spec.rotationSchedule.timeZone: Invalid value: "Europe/Atlantis": unknown time zone`,
+		},
+		{
+			name:             "time zone in cron expression",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "CRON_TZ=Europe/Paris 0 3 * * *"},
+			expectedMessage:  `spec.rotationSchedule.cron: Invalid value: "CRON_TZ=Europe/Paris 0 3 * * *": time zone must be set through timeZone`,
+		},
+		{
+			name:             "not aligned on wall-clock",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "@every 1h"},
+			expectedMessage:  `spec.rotationSchedule.cron: Invalid value: "@every 1h": must be aligned on wall-clock times, @every is not supported`,
+		},
+		{
+			name:             "never fires",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "0 3 30 2 *"},
+			expectedMessage:  `spec.rotationSchedule.cron: Invalid value: "0 3 30 2 *": never fires`,
+		},
+		{
+			name:             "too frequent",
+			rotationSchedule: networkingv1alpha1.RotationSchedule{Cron: "*/5 * * * *"},
+			expectedMessage:  `spec.rotationSchedule.cron: Invalid value: "*/5 * * * *": rotations must be at least 15m0s apart`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.RotationSchedule = &tc.rotationSchedule
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:        8 * time.Hour,
+				IngressHandoverDuration:   10 * time.Minute,
+				IngressLifetimeLowerBound: 15 * time.Minute,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_BlackoutWindow(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+
+	// The handover of the current Ingress would start now, in the middle of a blackout window.
+	currentIngress := testutils.ValidIngress.DeepCopy()
+	currentIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(testGracePeriod))
+	scheduledExpiresAt := clock.FixedNow.Add(testGracePeriod)
+
+	blackoutWindow := networkingv1alpha1.BlackoutWindow{
+		Name:  "release",
+		Start: &metav1.Time{Time: clock.FixedNow.Add(-time.Minute)},
+		End:   &metav1.Time{Time: clock.FixedNow.Add(5 * time.Minute)},
+	}
+
+	testCases := []struct {
+		name                string
+		maxRotationDeferral time.Duration
+		expectedExpiresAt   time.Time
+		expectedMessage     string
+	}{
+		{
+			name:              "deferred until the end of the window",
+			expectedExpiresAt: blackoutWindow.End.Add(testGracePeriod),
+			expectedMessage: fmt.Sprintf(`rotation of Ingress %s deferred from 2021-09-06T17:12:10Z to 2021-09-06T17:17:10Z by blackout window "release"`,
+				currentIngress.Name),
+		},
+		{
+			name:                "limited by the maximum deferral",
+			maxRotationDeferral: time.Minute,
+			expectedExpiresAt:   scheduledExpiresAt.Add(time.Minute),
+			expectedMessage: fmt.Sprintf(`rotation of Ingress %s deferred from 2021-09-06T17:12:10Z to 2021-09-06T17:13:10Z by blackout window "release", `+
+				"limited by the maximum deferral of 1m0s", currentIngress.Name),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			randomIngress := testutils.ValidRandomIng.DeepCopy()
+			randomIngress.Spec.BlackoutWindows = []networkingv1alpha1.BlackoutWindow{blackoutWindow}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			testClient, statusClient := newClientMock(ctrl)
+			updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+			gomock.InOrder(
+				expectGetRandomIngress(testClient, randomIngress, nil),
+				expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{currentIngress}, nil),
+				updateStatusCall,
+			)
+
+			reconciler := RandomIngressReconciler{
+				Client:                  testClient,
+				Scheme:                  scheme.Scheme,
+				Clock:                   clock,
+				TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+				IngressMaxLifetime:      testMaxLifetime,
+				IngressHandoverDuration: testGracePeriod,
+				MaxRotationDeferral:     tc.maxRotationDeferral,
+			}
+
+			res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedExpiresAt.Sub(clock.FixedNow)-testGracePeriod, res.RequeueAfter)
+
+			expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, tc.expectedExpiresAt)
+			setExpectedCondition(expectedStatus, networkingv1alpha1.RandomIngressCondition{
+				Type:               networkingv1alpha1.RandomIngressRotationDeferred,
+				Status:             corev1.ConditionTrue,
+				Reason:             blackoutWindowReason,
+				Message:            tc.expectedMessage,
+				LastHeartbeatTime:  metav1.NewTime(clock.FixedNow),
+				LastTransitionTime: metav1.NewTime(clock.FixedNow),
+			})
+			setExpectedActiveIngresses(expectedStatus,
+				newExpectedActiveIngress(currentIngress, currentIngress.CreationTimestamp.Time, tc.expectedExpiresAt, networkingv1alpha1.ActiveIngressActive))
+
+			assertStatusEquivalent(t, expectedStatus, actualStatus)
+		})
+	}
+}
+
+func TestDeferRotation(t *testing.T) {
+	handoverStart := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	absoluteWindow := func(name string, start, end time.Time) networkingv1alpha1.BlackoutWindow {
+		return networkingv1alpha1.BlackoutWindow{Name: name, Start: &metav1.Time{Time: start}, End: &metav1.Time{Time: end}}
+	}
+
+	testCases := []struct {
+		name               string
+		windows            []networkingv1alpha1.BlackoutWindow
+		expected           time.Time
+		expectedDeferredBy string
+	}{
+		{
+			name:     "no window",
+			expected: handoverStart,
+		},
+		{
+			name: "window after the handover",
+			windows: []networkingv1alpha1.BlackoutWindow{
+				absoluteWindow("later", handoverStart.Add(11*time.Minute), handoverStart.Add(time.Hour)),
+			},
+			expected: handoverStart,
+		},
+		{
+			name: "weekly window",
+			windows: []networkingv1alpha1.BlackoutWindow{{
+				Name: "monday evening",
+				Weekly: &networkingv1alpha1.WeeklyWindow{
+					Days:      []networkingv1alpha1.Weekday{"Monday"},
+					StartTime: "19:00",
+					Duration:  metav1.Duration{Duration: 2 * time.Hour},
+					TimeZone:  "Europe/Paris",
+				},
+			}},
+			// 21:00 in Paris is 19:00 UTC during summer time.
+			expected:           time.Date(2021, time.September, 06, 19, 0, 0, 0, time.UTC),
+			expectedDeferredBy: "monday evening",
+		},
+		{
+			name: "weekly window started the previous week",
+			windows: []networkingv1alpha1.BlackoutWindow{{
+				Name: "long week",
+				Weekly: &networkingv1alpha1.WeeklyWindow{
+					Days:      []networkingv1alpha1.Weekday{"Wednesday"},
+					StartTime: "00:00",
+					Duration:  metav1.Duration{Duration: 6 * 24 * time.Hour},
+				},
+			}},
+			expected:           time.Date(2021, time.September, 07, 0, 0, 0, 0, time.UTC),
+			expectedDeferredBy: "long week",
+		},
+		{
+			name: "chained windows",
+			windows: []networkingv1alpha1.BlackoutWindow{
+				absoluteWindow("first", handoverStart.Add(-time.Hour), handoverStart.Add(30*time.Minute)),
+				absoluteWindow("overlapping", handoverStart.Add(20*time.Minute), handoverStart.Add(40*time.Minute)),
+				absoluteWindow("too close", handoverStart.Add(45*time.Minute), handoverStart.Add(time.Hour)),
+			},
+			expected:           handoverStart.Add(time.Hour),
+			expectedDeferredBy: "too close",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, deferredBy := deferRotation(tc.windows, handoverStart, 10*time.Minute)
+			assert.Equal(t, tc.expected, actual.UTC())
+
+			if tc.expectedDeferredBy == "" {
+				assert.Nil(t, deferredBy)
+			} else if assert.NotNil(t, deferredBy) {
+				assert.Equal(t, tc.expectedDeferredBy, deferredBy.name)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ValidateBlackoutWindows(t *testing.T) {
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		window          networkingv1alpha1.BlackoutWindow
+		expectedMessage string
+	}{
+		{
+			name: "valid absolute window",
+			window: networkingv1alpha1.BlackoutWindow{
+				Start: &metav1.Time{Time: now},
+				End:   &metav1.Time{Time: now.Add(time.Hour)},
+			},
+		},
+		{
+			name: "valid weekly window",
+			window: networkingv1alpha1.BlackoutWindow{
+				Weekly: &networkingv1alpha1.WeeklyWindow{
+					Days:      []networkingv1alpha1.Weekday{"Saturday", "Sunday"},
+					StartTime: "00:00",
+					Duration:  metav1.Duration{Duration: 24 * time.Hour},
+					TimeZone:  "Europe/Paris",
+				},
+			},
+		},
+		{
+			name:            "empty window",
+			window:          networkingv1alpha1.BlackoutWindow{Name: "empty"},
+			expectedMessage: "spec.blackoutWindows[0]: Required value: start and end, or weekly, must be set",
+		},
+		{
+			name: "end before start",
+			window: networkingv1alpha1.BlackoutWindow{
+				Start: &metav1.Time{Time: now},
+				End:   &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+			expectedMessage: `spec.blackoutWindows[0].end: Invalid value: "2021-09-06T16:12:00Z": must be after start`,
+		},
+		{
+			name: "both absolute and weekly",
+			window: networkingv1alpha1.BlackoutWindow{
+				Name:  "both",
+				Start: &metav1.Time{Time: now},
+				Weekly: &networkingv1alpha1.WeeklyWindow{
+					Days:      []networkingv1alpha1.Weekday{"Monday"},
+					StartTime: "10:00",
+					Duration:  metav1.Duration{Duration: time.Hour},
+				},
+			},
+			expectedMessage: `spec.blackoutWindows[0]: Invalid value: "both": must be either an absolute or a weekly window`,
+		},
+		{
+			name: "invalid weekly window",
+			window: networkingv1alpha1.BlackoutWindow{
+				Weekly: &networkingv1alpha1.WeeklyWindow{
+					Days:      []networkingv1alpha1.Weekday{"Funday"},
+					StartTime: "25:00",
+					Duration:  metav1.Duration{Duration: 8 * 24 * time.Hour},
+					TimeZone:  "Europe/Atlantis",
+				},
+			},
+			expectedMessage: "[" +
+				`spec.blackoutWindows[0].weekly.days[0]: Unsupported value: "Funday": supported values: "Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", ` +
+				`spec.blackoutWindows[0].weekly.startTime: Invalid value: "25:00": must be in HH:MM format, ` +
+				`spec.blackoutWindows[0].weekly.duration: Invalid value: "192h0m0s": must be positive and at most a week, ` +
+				`spec.blackoutWindows[0].weekly.timeZone: Invalid value: "Europe/Atlantis": unknown time zone` +
+				"]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.BlackoutWindows = []networkingv1alpha1.BlackoutWindow{tc.window}
+
+			reconciler := RandomIngressReconciler{
+				Clock:                     testutils.FakeClock{FixedNow: now},
+				IngressMaxLifetime:        8 * time.Hour,
+				IngressHandoverDuration:   10 * time.Minute,
+				IngressLifetimeLowerBound: 15 * time.Minute,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ReadinessGate(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+
+	maxExtension := 30 * time.Second
+
+	loadBalancer := func(ip string) networkingv1.IngressStatus {
+		return networkingv1.IngressStatus{
+			LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+				Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: ip}},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		matchAddresses  bool
+		oldExpiredSince time.Duration
+		oldStatus       networkingv1.IngressStatus
+		newStatus       networkingv1.IngressStatus
+		expectDeletion  bool
+		expectedDelay   time.Duration
+		expectedReason  string
+		expectedRequeue time.Duration
+		expectedStatus  corev1.ConditionStatus
+	}{
+		{
+			name:            "new Ingress not served",
+			oldExpiredSince: time.Second,
+			expectedReason:  newIngressNotReadyReason,
+			expectedRequeue: maxExtension - time.Second,
+			expectedStatus:  corev1.ConditionTrue,
+		},
+		{
+			name:            "new Ingress served",
+			oldExpiredSince: time.Second,
+			newStatus:       loadBalancer("10.0.0.2"),
+			expectDeletion:  true,
+			expectedReason:  singleIngressLiveReason,
+			expectedRequeue: testMaxLifetime - 2*testGracePeriod,
+			expectedStatus:  corev1.ConditionFalse,
+		},
+		{
+			name:            "new Ingress served on other addresses",
+			matchAddresses:  true,
+			oldExpiredSince: time.Second,
+			oldStatus:       loadBalancer("10.0.0.1"),
+			newStatus:       loadBalancer("10.0.0.2"),
+			expectedReason:  newIngressNotReadyReason,
+			expectedRequeue: maxExtension - time.Second,
+			expectedStatus:  corev1.ConditionTrue,
+		},
+		{
+			name:            "max extension exceeded",
+			oldExpiredSince: maxExtension,
+			expectDeletion:  true,
+			expectedReason:  singleIngressLiveReason,
+			expectedRequeue: testMaxLifetime - 2*testGracePeriod,
+			expectedStatus:  corev1.ConditionFalse,
+		},
+		{
+			name:            "deleted late after the max extension",
+			oldExpiredSince: maxExtension + 5*time.Second,
+			expectDeletion:  true,
+			expectedDelay:   5 * time.Second,
+			expectedReason:  singleIngressLiveReason,
+			expectedRequeue: testMaxLifetime - 2*testGracePeriod,
+			expectedStatus:  corev1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			randomIngress := testutils.ValidRandomIng.DeepCopy()
+			randomIngress.Spec.ReadinessGate = &networkingv1alpha1.ReadinessGate{
+				MatchAddresses: tc.matchAddresses,
+				MaxExtension:   &metav1.Duration{Duration: maxExtension},
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			oldIngress := testutils.ValidIngress.DeepCopy()
+			oldIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(-tc.oldExpiredSince))
+			oldIngress.Status = tc.oldStatus
+
+			// The new Ingress was created when the handover of the old one started.
+			newIngress := testutils.ValidIngress.DeepCopy()
+			newIngress.Name = fmt.Sprintf("%s-%s-987fed65", randomIngress.Name, hash.RandomIngressSpec(&randomIngress.Spec))
+			newIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testGracePeriod))
+			newIngress.Status = tc.newStatus
+
+			testClient, statusClient := newClientMock(ctrl)
+			updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+			calls := []*gomock.Call{
+				expectGetRandomIngress(testClient, randomIngress, nil),
+				expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{oldIngress, newIngress}, nil),
+			}
+			if tc.expectDeletion {
+				calls = append(calls, expectDeleteIngress(testClient, oldIngress, nil))
+			}
+			gomock.InOrder(append(calls, updateStatusCall)...)
+
+			reconciler := RandomIngressReconciler{
+				Client:                  testClient,
+				Scheme:                  scheme.Scheme,
+				Clock:                   clock,
+				TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+				IngressMaxLifetime:      testMaxLifetime,
+				IngressHandoverDuration: testGracePeriod,
+			}
+
+			res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRequeue, res.RequeueAfter)
+
+			progressing := getCondition(*actualStatus, networkingv1alpha1.RandomIngressProgressing)
+			if assert.NotNil(t, progressing) {
+				assert.Equal(t, tc.expectedStatus, progressing.Status)
+				assert.Equal(t, tc.expectedReason, progressing.Reason)
+			}
+
+			notReady := getCondition(*actualStatus, networkingv1alpha1.RandomIngressNewIngressNotReady)
+			if assert.NotNil(t, notReady) {
+				if tc.expectDeletion {
+					assert.Equal(t, corev1.ConditionFalse, notReady.Status)
+					assert.Equal(t, noIngressHeldReason, notReady.Reason)
+				} else {
+					assert.Equal(t, corev1.ConditionTrue, notReady.Status)
+					assert.Equal(t, newIngressNotReadyReason, notReady.Reason)
+					assert.Equal(t, progressing.Message, notReady.Message)
+				}
+			}
+
+			// Keeping an expired Ingress until its replacement is served does not count as a deletion delay.
+			if tc.expectDeletion && assert.NotNil(t, actualStatus.LastIngressDeletion) {
+				assert.Equal(t, tc.expectedDelay, actualStatus.LastIngressDeletion.Delay.Duration)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_NextRotationEvent(t *testing.T) {
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	createdAt := func(ago time.Duration) client.Object {
+		ingress := testutils.ValidIngress.DeepCopy()
+		ingress.CreationTimestamp = metav1.NewTime(now.Add(-ago))
+		return ingress
+	}
+
+	testCases := []struct {
+		name          string
+		ingresses     []client.Object
+		readinessGate *readinessGate
+		expectedEvent time.Time
+	}{
+		{
+			name:          "no Ingress",
+			expectedEvent: time.Time{},
+		},
+		{
+			name:          "expiration of the oldest Ingress",
+			ingresses:     []client.Object{createdAt(testGracePeriod / 2), createdAt(testMaxLifetime - testGracePeriod/2)},
+			expectedEvent: now.Add(testGracePeriod / 2),
+		},
+		{
+			name: "handover start of an Ingress listed in between",
+			ingresses: []client.Object{
+				createdAt(testMaxLifetime - 2*testGracePeriod),
+				createdAt(testMaxLifetime - testGracePeriod - time.Second),
+				createdAt(time.Minute),
+			},
+			expectedEvent: now.Add(time.Second),
+		},
+		{
+			name:          "end of the maximum extension of an expired Ingress",
+			ingresses:     []client.Object{createdAt(time.Second), createdAt(testMaxLifetime + 5*time.Second)},
+			readinessGate: &readinessGate{maxExtension: 10 * time.Second},
+			expectedEvent: now.Add(5 * time.Second),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := RandomIngressReconciler{Clock: testutils.FakeClock{FixedNow: now}}
+			settings := rotationSettings{
+				maxLifetime:      testMaxLifetime,
+				handoverDuration: testGracePeriod,
+				readinessGate:    tc.readinessGate,
+			}
+
+			assert.Equal(t, tc.expectedEvent, reconciler.nextRotationEvent(tc.ingresses, settings))
+		})
+	}
+}
+
+func TestRandomIngressReconciler_IngressDeletionDelayMetric(t *testing.T) {
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	sampleCountAndSum := func() (uint64, float64) {
+		var metric dto.Metric
+		assert.NoError(t, ingressDeletionDelay.Write(&metric))
+		return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+	}
+	count, sum := sampleCountAndSum()
+
+	ingress := testutils.ValidIngress.DeepCopy()
+	ingress.CreationTimestamp = metav1.NewTime(now.Add(-testMaxLifetime).Add(-3 * time.Second))
+
+	reconciler := RandomIngressReconciler{Clock: testutils.FakeClock{FixedNow: now}}
+	settings := rotationSettings{maxLifetime: testMaxLifetime, handoverDuration: testGracePeriod}
+
+	var status networkingv1alpha1.RandomIngressStatus
+	reconciler.recordIngressDeletion(&status, ingress, settings)
+
+	newCount, newSum := sampleCountAndSum()
+	assert.Equal(t, count+1, newCount)
+	assert.InDelta(t, sum+3, newSum, 1e-9)
+	assert.Equal(t, 3*time.Second, status.LastIngressDeletion.Delay.Duration)
+}
+
+func TestRandomIngressReconciler_NewIngressNotReadyTransition(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+	heldSince := clock.FixedNow.Add(-5 * time.Second)
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.ReadinessGate = &networkingv1alpha1.ReadinessGate{}
+	randomIngress.Status.Conditions = []networkingv1alpha1.RandomIngressCondition{{
+		Type:               networkingv1alpha1.RandomIngressNewIngressNotReady,
+		Status:             corev1.ConditionTrue,
+		Reason:             newIngressNotReadyReason,
+		LastHeartbeatTime:  metav1.NewTime(heldSince),
+		LastTransitionTime: metav1.NewTime(heldSince),
+	}}
+
+	oldIngress := testutils.ValidIngress.DeepCopy()
+	oldIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(-10 * time.Second))
+
+	// The replacement got served since the last reconciliation.
+	newIngress := testutils.ValidIngress.DeepCopy()
+	newIngress.Name = fmt.Sprintf("%s-%s-987fed65", randomIngress.Name, hash.RandomIngressSpec(&randomIngress.Spec))
+	newIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testGracePeriod))
+	newIngress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.2"}}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{oldIngress, newIngress}, nil),
+		expectDeleteIngress(testClient, oldIngress, nil),
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                    testClient,
+		Scheme:                    scheme.Scheme,
+		Clock:                     clock,
+		TokenSource:               testutils.NewFakeTokenSource(t, []string{}),
+		IngressMaxLifetime:        testMaxLifetime,
+		IngressHandoverDuration:   testGracePeriod,
+		ReadinessGateMaxExtension: time.Hour,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	notReady := getCondition(*actualStatus, networkingv1alpha1.RandomIngressNewIngressNotReady)
+	if assert.NotNil(t, notReady) {
+		assert.Equal(t, corev1.ConditionFalse, notReady.Status)
+		assert.Equal(t, noIngressHeldReason, notReady.Reason)
+		assert.Equal(t, clock.FixedNow, notReady.LastTransitionTime.Time)
+	}
+}
+
+func TestRandomIngressReconciler_ReplacementFailed(t *testing.T) {
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+
+	createErr := errors.New("admission webhook denied the request")
+
+	// Both Ingresses are expired, the newest one is the last serving one.
+	olderIngress := testutils.ValidIngress.DeepCopy()
+	olderIngress.Name = "randomIngress-w2xvbv8f-0ld3r000"
+	olderIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(-time.Minute))
+
+	lastIngress := testutils.ValidIngress.DeepCopy()
+	lastIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow.Add(-testMaxLifetime).Add(-time.Second))
+
+	t.Run("last serving Ingress kept", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, _ := expectCreateIngress(testClient, createErr)
+
+		// The expired Ingress is not deleted, and the status is still updated before retrying.
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{lastIngress}, nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{"1a0c6f64-7d0e-4c5e-9b8a-3f2d1e0c9b8a"}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.ErrorIs(t, err, createErr)
+
+		replacementFailed := getCondition(*actualStatus, networkingv1alpha1.RandomIngressReplacementFailed)
+		if assert.NotNil(t, replacementFailed) {
+			assert.Equal(t, corev1.ConditionTrue, replacementFailed.Status)
+			assert.Equal(t, createFailedReason, replacementFailed.Reason)
+			assert.Equal(t, fmt.Sprintf("failed to create a new Ingress: %v, keeping Ingress %s", createErr, lastIngress.Name), replacementFailed.Message)
+		}
+
+		if assert.Len(t, actualStatus.ActiveIngresses, 1) {
+			assert.Equal(t, lastIngress.Name, actualStatus.ActiveIngresses[0].Name)
+		}
+	})
+
+	t.Run("last serving Ingress kept with an invalid spec", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		// Nothing is created, only the older expired Ingress is deleted.
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{olderIngress, lastIngress}, nil),
+			expectDeleteIngress(testClient, olderIngress, nil),
+			updateStatusCall,
+		)
+
+		// uuidv4 tokens only hold 122 bits.
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			MinTokenEntropyBits:     128,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		valid := getCondition(*actualStatus, networkingv1alpha1.RandomIngressValid)
+		if assert.NotNil(t, valid) {
+			assert.Equal(t, corev1.ConditionFalse, valid.Status)
+		}
+
+		if assert.Len(t, actualStatus.ActiveIngresses, 1) {
+			assert.Equal(t, lastIngress.Name, actualStatus.ActiveIngresses[0].Name)
+		}
+	})
+
+	t.Run("older expired Ingresses deleted", func(t *testing.T) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, _ := expectCreateIngress(testClient, createErr)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{olderIngress, lastIngress}, nil),
+			createIngressCall,
+			expectDeleteIngress(testClient, olderIngress, nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   clock,
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{"6b1f2e3d-4c5b-4a69-8f7e-0d1c2b3a4f5e"}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.ErrorIs(t, err, createErr)
+
+		replacementFailed := getCondition(*actualStatus, networkingv1alpha1.RandomIngressReplacementFailed)
+		if assert.NotNil(t, replacementFailed) {
+			assert.Equal(t, corev1.ConditionTrue, replacementFailed.Status)
+		}
+
+		if assert.Len(t, actualStatus.ActiveIngresses, 1) {
+			assert.Equal(t, lastIngress.Name, actualStatus.ActiveIngresses[0].Name)
+		}
+	})
+}
+
+func TestRandomIngressReconciler_DerivedToken(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "token-secret"},
+		Data:       map[string][]byte{"key": []byte("0123456789abcdef0123456789abcdef")},
+	}
+
+	// reconcileAt reconciles a RandomIngress without Ingress at the given time,
+	// and returns the created Ingress and the updated status.
+	reconcileAt := func(t *testing.T, now time.Time, secret *corev1.Secret, expectCreate bool) (*networkingv1.Ingress, *networkingv1alpha1.RandomIngressStatus, error) {
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.Token = &networkingv1alpha1.TokenSpec{
+			Format: networkingv1alpha1.TokenFormatBase32,
+			DerivedFrom: &networkingv1alpha1.TokenDerivation{
+				SecretKeyRef: corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+					Key:                  "key",
+				},
+			},
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		// The Secret is read from the API server rather than from the cache of the client.
+		apiReader := mock_client.NewMockClient(ctrl)
+
+		calls := []*gomock.Call{
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+			expectGetSecret(apiReader, secret),
+		}
+
+		var actualIngress *networkingv1.Ingress
+		if expectCreate {
+			var createIngressCall *gomock.Call
+			createIngressCall, actualIngress = expectCreateIngress(testClient, nil)
+			calls = append(calls, createIngressCall)
+		}
+		gomock.InOrder(append(calls, updateStatusCall)...)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			APIReader:               apiReader,
+			Scheme:                  scheme.Scheme,
+			Clock:                   testutils.FakeClock{FixedNow: now},
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			MinTokenEntropyBits:     128,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+
+		return actualIngress, actualStatus, err
+	}
+
+	t.Run("same host within an epoch", func(t *testing.T) {
+		// Two clusters reconciling at different times of the same epoch, from 17:12 to 17:14.
+		first, _, err := reconcileAt(t, time.Date(2021, time.September, 06, 17, 12, 5, 0, time.UTC), secret, true)
+		assert.NoError(t, err)
+		second, _, err := reconcileAt(t, time.Date(2021, time.September, 06, 17, 13, 40, 0, time.UTC), secret, true)
+		assert.NoError(t, err)
+
+		assert.Equal(t, first.Name, second.Name)
+		assert.Equal(t, first.Spec.Rules, second.Spec.Rules)
+		assert.Regexp(t, `^[a-z2-7]{26}\.example\.com$`, first.Spec.Rules[0].Host)
+		assert.Equal(t, "2021-09-06T17:14:00Z", first.Annotations[expiresAtAnnotation])
+		assert.Equal(t, "2021-09-06T17:14:00Z", second.Annotations[expiresAtAnnotation])
+	})
+
+	t.Run("next epoch within the handover duration", func(t *testing.T) {
+		current, _, err := reconcileAt(t, time.Date(2021, time.September, 06, 17, 13, 40, 0, time.UTC), secret, true)
+		assert.NoError(t, err)
+		next, _, err := reconcileAt(t, time.Date(2021, time.September, 06, 17, 13, 55, 0, time.UTC), secret, true)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, current.Spec.Rules[0].Host, next.Spec.Rules[0].Host)
+		assert.Equal(t, "2021-09-06T17:16:00Z", next.Annotations[expiresAtAnnotation])
+	})
+
+	t.Run("key too short", func(t *testing.T) {
+		shortSecret := secret.DeepCopy()
+		shortSecret.Data["key"] = []byte("0123456789")
+
+		_, status, err := reconcileAt(t, time.Date(2021, time.September, 06, 17, 12, 5, 0, time.UTC), shortSecret, false)
+		assert.EqualError(t, err, "key key of token secret token-secret must hold at least 128 bits")
+
+		replacementFailed := getCondition(*status, networkingv1alpha1.RandomIngressReplacementFailed)
+		if assert.NotNil(t, replacementFailed) {
+			assert.Equal(t, corev1.ConditionTrue, replacementFailed.Status)
+		}
+	})
+}
+
+func TestRandomIngressReconciler_NamedPlaceholders(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate.Spec.Rules = []networkingv1.IngressRule{
+		{Host: "|RANDOM:admin|.admin.example.com"},
+		{Host: "|RANDOM:public|.example.com"},
+	}
+	randomIngress.Spec.Placeholders = []networkingv1alpha1.Placeholder{
+		{Name: "admin", MaxLifetime: &metav1.Duration{Duration: time.Minute}},
+	}
+
+	// reconcileAt reconciles the RandomIngress with the given Ingresses at the given time,
+	// and returns the created Ingress and the updated status.
+	reconcileAt := func(t *testing.T, now time.Time, existingIngresses []*networkingv1.Ingress, tokens []string) (*networkingv1.Ingress, *networkingv1alpha1.RandomIngressStatus) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngresses(testClient, "default", "randomIngress", existingIngresses, nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		testTokenSource := testutils.NewFakeTokenSource(t, tokens)
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   testutils.FakeClock{FixedNow: now},
+			TokenSource:             testTokenSource,
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+		assert.Len(t, testTokenSource.Items, 0)
+
+		return actualIngress, actualStatus
+	}
+
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	// Tokens are generated in the order of the names of the placeholders.
+	first, firstStatus := reconcileAt(t, now, []*networkingv1.Ingress{}, []string{"admin-token-1", "public-token-1"})
+	assert.Equal(t, "admin-token-1.admin.example.com", first.Spec.Rules[0].Host)
+	assert.Equal(t, "public-token-1.example.com", first.Spec.Rules[1].Host)
+
+	// The Ingress expires along with the admin token.
+	assert.Equal(t, "2021-09-06T17:13:00Z", first.Annotations[expiresAtAnnotation])
+	assert.Equal(t, []networkingv1alpha1.TokenStatus{
+		{Name: "admin", Value: "admin-token-1", ExpiresAt: metav1.NewTime(now.Add(time.Minute))},
+		{Name: "public", Value: "public-token-1", ExpiresAt: metav1.NewTime(now.Add(testMaxLifetime))},
+	}, firstStatus.Tokens)
+
+	// Within the handover duration of the admin token, only the admin token is replaced.
+	handoverStart := now.Add(time.Minute).Add(-testGracePeriod).Add(5 * time.Second)
+	second, secondStatus := reconcileAt(t, handoverStart, []*networkingv1.Ingress{first}, []string{"admin-token-2"})
+	assert.Equal(t, "admin-token-2.admin.example.com", second.Spec.Rules[0].Host)
+	assert.Equal(t, "public-token-1.example.com", second.Spec.Rules[1].Host)
+	assert.NotEqual(t, first.Name, second.Name)
+
+	assert.Equal(t, "2021-09-06T17:13:55Z", second.Annotations[expiresAtAnnotation])
+	assert.Equal(t, []networkingv1alpha1.TokenStatus{
+		{Name: "admin", Value: "admin-token-2", ExpiresAt: metav1.NewTime(handoverStart.Add(time.Minute))},
+		{Name: "public", Value: "public-token-1", ExpiresAt: metav1.NewTime(now.Add(testMaxLifetime))},
+	}, secondStatus.Tokens)
+}
+
+func TestRandomIngressReconciler_ValidatePlaceholders(t *testing.T) {
+	testCases := []struct {
+		name            string
+		hosts           []string
+		placeholders    []networkingv1alpha1.Placeholder
+		expectedMessage string
+	}{
+		{
+			name:  "named placeholders",
+			hosts: []string{"|RANDOM:admin|.example.com", "|RANDOM|.|RANDOM:public|.example.com"},
+			placeholders: []networkingv1alpha1.Placeholder{
+				{Name: "admin", MaxLifetime: &metav1.Duration{Duration: time.Hour}},
+				{Name: "public", Token: &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatBase36}},
+			},
+		},
+		{
+			name:            "rule without placeholder",
+			hosts:           []string{"|RANDOM:admin|.example.com", "|RANDOM:Admin|.example.com"},
+			expectedMessage: `spec.ingressTemplate.spec.rules[1].host: Invalid value: "|RANDOM:Admin|.example.com": missing |RANDOM| placeholder`,
+		},
+		{
+			name:  "unused placeholder",
+			hosts: []string{"|RANDOM:admin|.example.com"},
+			placeholders: []networkingv1alpha1.Placeholder{
+				{Name: "public"},
+			},
+			expectedMessage: `spec.placeholders[0].name: Invalid value: "public": is not used by the template`,
+		},
+		{
+			name:  "duplicate placeholder",
+			hosts: []string{"|RANDOM:admin|.example.com"},
+			placeholders: []networkingv1alpha1.Placeholder{
+				{Name: "admin"},
+				{Name: "admin"},
+			},
+			expectedMessage: `spec.placeholders[1].name: Duplicate value: "admin"`,
+		},
+		{
+			name:  "lifetime shorter than the handover",
+			hosts: []string{"|RANDOM:admin|.example.com"},
+			placeholders: []networkingv1alpha1.Placeholder{
+				{Name: "admin", MaxLifetime: &metav1.Duration{Duration: 5 * time.Minute}},
+			},
+			expectedMessage: `spec.placeholders[0].maxLifetime: Invalid value: "5m0s": must be longer than the handover duration (10m0s)`,
+		},
+		{
+			name:  "invalid token",
+			hosts: []string{"|RANDOM:admin|.example.com"},
+			placeholders: []networkingv1alpha1.Placeholder{
+				{Name: "admin", Token: &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatHex, EntropyBits: testutils.Int32Ptr(64)}},
+			},
+			expectedMessage: "spec.placeholders[0].token.entropyBits: Invalid value: 64: gives 64 bits of entropy, must give at least 122",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate.Spec.Rules = nil
+			for _, host := range tc.hosts {
+				spec.IngressTemplate.Spec.Rules = append(spec.IngressTemplate.Spec.Rules, networkingv1.IngressRule{Host: host})
+			}
+			spec.Placeholders = tc.placeholders
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_RandomPath(t *testing.T) {
+	prefix := networkingv1.PathTypePrefix
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate.Spec.Rules = []networkingv1.IngressRule{
+		{
+			Host: "www.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{Path: "/|RANDOM|/api", PathType: &prefix},
+						{Path: "/|RANDOM|/static", PathType: &prefix},
+					},
+				},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+		createIngressCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	valid := getCondition(*actualStatus, networkingv1alpha1.RandomIngressValid)
+	if assert.NotNil(t, valid) {
+		assert.Equal(t, corev1.ConditionTrue, valid.Status)
+	}
+
+	assert.Equal(t, "www.example.com", actualIngress.Spec.Rules[0].Host)
+	assert.Equal(t, "/6900d1a3-798c-4d9a-9a2f-737c72046efa/api", actualIngress.Spec.Rules[0].HTTP.Paths[0].Path)
+	assert.Equal(t, "/6900d1a3-798c-4d9a-9a2f-737c72046efa/static", actualIngress.Spec.Rules[0].HTTP.Paths[1].Path)
+
+	// The template must not be modified.
+	assert.Equal(t, "/|RANDOM|/api", randomIngress.Spec.IngressTemplate.Spec.Rules[0].HTTP.Paths[0].Path)
+}
+
+func TestRandomIngressReconciler_ValidatePaths(t *testing.T) {
+	exact := networkingv1.PathTypeExact
+	prefix := networkingv1.PathTypePrefix
+	implementationSpecific := networkingv1.PathTypeImplementationSpecific
+
+	testCases := []struct {
+		name            string
+		host            string
+		paths           []networkingv1.HTTPIngressPath
+		expectedMessage string
+	}{
+		{
+			name: "placeholder in every path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/|RANDOM|/api", PathType: &prefix},
+				{Path: "/|RANDOM:assets|", PathType: &exact},
+			},
+		},
+		{
+			name: "placeholder in the host",
+			host: "|RANDOM|.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/api", PathType: &prefix},
+			},
+		},
+		{
+			name: "placeholder missing from a path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/|RANDOM|/api", PathType: &prefix},
+				{Path: "/static", PathType: &prefix},
+			},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].http.paths[1].path: Invalid value: "/static": ` +
+				`missing |RANDOM| placeholder, required in every path when the host has none`,
+		},
+		{
+			name: "placeholder missing from every path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/api", PathType: &prefix},
+			},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].host: Invalid value: "www.example.com": missing |RANDOM| placeholder`,
+		},
+		{
+			name: "relative path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "|RANDOM|/api", PathType: &implementationSpecific},
+			},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].http.paths[0].path: Invalid value: "|RANDOM|/api": must be an absolute path`,
+		},
+		{
+			name: "invalid prefix path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/|RANDOM|//api/.", PathType: &prefix},
+			},
+			expectedMessage: `[spec.ingressTemplate.spec.rules[0].http.paths[0].path: Invalid value: "/|RANDOM|//api/.": must not contain '//', ` +
+				`spec.ingressTemplate.spec.rules[0].http.paths[0].path: Invalid value: "/|RANDOM|//api/.": must not end with '/.']`,
+		},
+		{
+			name: "implementation specific path",
+			host: "www.example.com",
+			paths: []networkingv1.HTTPIngressPath{
+				{Path: "/|RANDOM|//api/.", PathType: &implementationSpecific},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate.Spec.Rules = []networkingv1.IngressRule{
+				{
+					Host: tc.host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{Paths: tc.paths},
+					},
+				},
+			}
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_TLS(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate.Spec.TLS = []networkingv1.IngressTLS{
+		{Hosts: []string{"|RANDOM|.example.com", "www.|RANDOM|.example.com"}, SecretName: "tls-|RANDOM|"},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+		createIngressCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []networkingv1.IngressTLS{
+		{
+			Hosts:      []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", "www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"},
+			SecretName: "tls-6900d1a3-798c-4d9a-9a2f-737c72046efa",
+		},
+	}, actualIngress.Spec.TLS)
+
+	// The template must not be modified.
+	assert.Equal(t, "tls-|RANDOM|", randomIngress.Spec.IngressTemplate.Spec.TLS[0].SecretName)
+
+	perHostCertificate := getCondition(*actualStatus, networkingv1alpha1.RandomIngressPerHostCertificateRequired)
+	if assert.NotNil(t, perHostCertificate) {
+		assert.Equal(t, corev1.ConditionTrue, perHostCertificate.Status)
+		assert.Equal(t, randomTLSHostsReason, perHostCertificate.Reason)
+		assert.Equal(t, "TLS hosts |RANDOM|.example.com, www.|RANDOM|.example.com need a certificate issued for every generated Ingress",
+			perHostCertificate.Message)
+	}
+}
+
+func TestRandomIngressReconciler_ValidateTLS(t *testing.T) {
+	testCases := []struct {
+		name            string
+		tls             []networkingv1.IngressTLS
+		expectedMessage string
+	}{
+		{
+			name: "random hosts",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"|RANDOM|.example.com", "www.|RANDOM|.example.com"}, SecretName: "tls-|RANDOM|"}},
+		},
+		{
+			name: "wildcard hosts",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"*.example.com", "*.|RANDOM|.example.com"}, SecretName: "wildcard-tls"}},
+		},
+		{
+			name:            "host without rule",
+			tls:             []networkingv1.IngressTLS{{Hosts: []string{"|RANDOM|.example.org", "*.other.example.com"}}},
+			expectedMessage: `[spec.ingressTemplate.spec.tls[0].hosts[0]: Invalid value: "|RANDOM|.example.org": does not match any rule host, spec.ingressTemplate.spec.tls[0].hosts[1]: Invalid value: "*.other.example.com": does not match any rule host]`,
+		},
+		{
+			name: "invalid secret name",
+			tls:  []networkingv1.IngressTLS{{Hosts: []string{"|RANDOM|.example.com"}, SecretName: "TLS_|RANDOM|"}},
+			expectedMessage: `spec.ingressTemplate.spec.tls[0].secretName: Invalid value: "TLS_|RANDOM|": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', ` +
+				`and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate.Spec.TLS = tc.tls
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_TemplateMetadata(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate.Metadata.Annotations = map[string]string{
+		"external-dns.alpha.kubernetes.io/hostname": "|RANDOM|.example.com",
+		"nginx.ingress.kubernetes.io/auth-signin":   "https://|RANDOM|.example.com/oauth2/start?rd=$escaped_request_uri",
+	}
+	randomIngress.Spec.IngressTemplate.Metadata.Labels = map[string]string{
+		"random-host": "|RANDOM|",
+		"team":        "checkout",
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, _ := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+		createIngressCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", actualIngress.Annotations["external-dns.alpha.kubernetes.io/hostname"])
+	assert.Equal(t, "https://6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com/oauth2/start?rd=$escaped_request_uri",
+		actualIngress.Annotations["nginx.ingress.kubernetes.io/auth-signin"])
+	assert.Equal(t, map[string]string{
+		"random-host": "6900d1a3-798c-4d9a-9a2f-737c72046efa",
+		"team":        "checkout",
+	}, actualIngress.Labels)
+
+	// The template must not be modified.
+	assert.Equal(t, "|RANDOM|", randomIngress.Spec.IngressTemplate.Metadata.Labels["random-host"])
+}
+
+func TestRandomIngressReconciler_ValidateLabels(t *testing.T) {
+	testCases := []struct {
+		name            string
+		labels          map[string]string
+		token           *networkingv1alpha1.TokenSpec
+		expectedMessage string
+	}{
+		{
+			name:   "random label",
+			labels: map[string]string{"random-host": "host-|RANDOM|"},
+		},
+		{
+			name:            "too long once substituted",
+			labels:          map[string]string{"random-host": "a-rather-long-prefix-for-a-label-|RANDOM|"},
+			expectedMessage: `spec.ingressTemplate.metadata.labels[random-host]: Invalid value: "a-rather-long-prefix-for-a-label-|RANDOM|": must be no more than 63 characters`,
+		},
+		{
+			name:   "short tokens",
+			labels: map[string]string{"random-host": "a-rather-long-prefix-for-a-label-|RANDOM|"},
+			token:  &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatBase36, EntropyBits: testutils.Int32Ptr(128)},
+		},
+		{
+			name:   "invalid characters",
+			labels: map[string]string{"random-host": "|RANDOM|_"},
+			expectedMessage: `spec.ingressTemplate.metadata.labels[random-host]: Invalid value: "|RANDOM|_": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', ` +
+				`and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate.Metadata.Labels = tc.labels
+			spec.Token = tc.token
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ValidateHosts(t *testing.T) {
+	testCases := []struct {
+		name            string
+		hosts           []string
+		token           *networkingv1alpha1.TokenSpec
+		expectedMessage string
+	}{
+		{
+			name:  "valid hosts",
+			hosts: []string{"|RANDOM|.example.com", "*.|RANDOM|.example.com"},
+		},
+		{
+			name:  "label too long once substituted",
+			hosts: []string{"a-rather-long-prefix-|RANDOM|-suffix.example.com"},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].host: Invalid value: "a-rather-long-prefix-|RANDOM|-suffix.example.com": ` +
+				`label "a-rather-long-prefix-|RANDOM|-suffix" must be no more than 63 characters once substituted, got up to 64`,
+		},
+		{
+			name:  "shorter token",
+			hosts: []string{"a-rather-long-prefix-|RANDOM|-suffix.example.com"},
+			token: &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatBase36, EntropyBits: testutils.Int32Ptr(128)},
+		},
+		{
+			name:  "invalid characters",
+			hosts: []string{"|RANDOM|.Example.com"},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].host: Invalid value: "|RANDOM|.Example.com": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', ` +
+				`and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:            "misplaced wildcard",
+			hosts:           []string{"www.*.|RANDOM|.example.com"},
+			expectedMessage: `spec.ingressTemplate.spec.rules[0].host: Invalid value: "www.*.|RANDOM|.example.com": a wildcard must be the whole first label of the host, as in *.example.com`,
+		},
+		{
+			name:            "duplicate hosts",
+			hosts:           []string{"|RANDOM|.example.com", "www.|RANDOM|.example.com", "|RANDOM|.example.com"},
+			expectedMessage: `spec.ingressTemplate.spec.rules[2].host: Duplicate value: "|RANDOM|.example.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate.Spec.Rules = nil
+			for _, host := range tc.hosts {
+				spec.IngressTemplate.Spec.Rules = append(spec.IngressTemplate.Spec.Rules, networkingv1.IngressRule{Host: host})
+			}
+			spec.Token = tc.token
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_HostConflicts(t *testing.T) {
+	foreignIngress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other-team", Name: "their-ingress"},
+	}
+
+	t.Run("generated host in use", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, &testutils.ValidRandomIng, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+			expectListIngressesByHost(testClient, "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", []*networkingv1.Ingress{foreignIngress}),
+			expectListIngressesByHost(testClient, "www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", nil),
+			expectListIngressesByHost(testClient, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1.example.com", nil),
+			expectListIngressesByHost(testClient, "www.0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1.example.com", nil),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client: testClient,
+			Scheme: scheme.Scheme,
+			Clock:  testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+			TokenSource: testutils.NewFakeTokenSource(t, []string{
+				"6900d1a3-798c-4d9a-9a2f-737c72046efa",
+				"0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1",
+			}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			DetectHostConflicts:     true,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1.example.com", actualIngress.Spec.Rules[0].Host)
+
+		hostConflict := getCondition(*actualStatus, networkingv1alpha1.RandomIngressHostConflict)
+		if assert.NotNil(t, hostConflict) {
+			assert.Equal(t, corev1.ConditionFalse, hostConflict.Status)
+			assert.Equal(t, noHostConflictReason, hostConflict.Reason)
+		}
+	})
+
+	t.Run("same tokens generated again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, &testutils.ValidRandomIng, nil),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+			expectListIngressesByHost(testClient, "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", []*networkingv1.Ingress{foreignIngress}),
+			expectListIngressesByHost(testClient, "www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", nil),
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client: testClient,
+			Scheme: scheme.Scheme,
+			Clock:  testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+			TokenSource: testutils.NewFakeTokenSource(t, []string{
+				"6900d1a3-798c-4d9a-9a2f-737c72046efa",
+				"6900d1a3-798c-4d9a-9a2f-737c72046efa",
+			}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			DetectHostConflicts:     true,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.EqualError(t, err, "generated hosts are already in use: 6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com (other-team/their-ingress)")
+
+		hostConflict := getCondition(*actualStatus, networkingv1alpha1.RandomIngressHostConflict)
+		if assert.NotNil(t, hostConflict) {
+			assert.Equal(t, corev1.ConditionTrue, hostConflict.Status)
+			assert.Equal(t, hostsInUseReason, hostConflict.Reason)
+			assert.Equal(t, "hosts served by other Ingresses: 6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com (other-team/their-ingress)", hostConflict.Message)
+		}
+
+		replacementFailed := getCondition(*actualStatus, networkingv1alpha1.RandomIngressReplacementFailed)
+		if assert.NotNil(t, replacementFailed) {
+			assert.Equal(t, corev1.ConditionTrue, replacementFailed.Status)
+		}
+	})
+
+	t.Run("random path in use under a static host", func(t *testing.T) {
+		prefix := networkingv1.PathTypePrefix
+
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.IngressTemplate.Spec.Rules = []networkingv1.IngressRule{
+			{
+				Host: "www.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/|RANDOM|", PathType: &prefix}},
+					},
+				},
+			},
+		}
+
+		servingPath := func(namespace, name, path string) *networkingv1.Ingress {
+			return &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{
+						Host: "www.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{
+							HTTP: &networkingv1.HTTPIngressRuleValue{
+								Paths: []networkingv1.HTTPIngressPath{{Path: path, PathType: &prefix}},
+							},
+						},
+					}},
+				},
+			}
+		}
+
+		// Ingresses generated by the RandomIngress itself are not conflicts, nor other paths of the static host.
+		ownIngress := servingPath("default", "randomIngress-previous", "/6900d1a3-798c-4d9a-9a2f-737c72046efa")
+		ownIngress.OwnerReferences = []metav1.OwnerReference{
+			*metav1.NewControllerRef(randomIngress, networkingv1alpha1.GroupVersion.WithKind("RandomIngress")),
+		}
+		sameHostIngress := servingPath("other-team", "their-website", "/")
+		samePathIngress := servingPath("other-team", "their-ingress", "/6900d1a3-798c-4d9a-9a2f-737c72046efa")
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngressesByHost(testClient, "www.example.com", []*networkingv1.Ingress{ownIngress, sameHostIngress, samePathIngress}),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+			expectListIngressesByHost(testClient, "www.example.com", []*networkingv1.Ingress{ownIngress, sameHostIngress, samePathIngress}),
+			expectListIngressesByHost(testClient, "www.example.com", []*networkingv1.Ingress{ownIngress, sameHostIngress, samePathIngress}),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client: testClient,
+			Scheme: scheme.Scheme,
+			Clock:  testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+			TokenSource: testutils.NewFakeTokenSource(t, []string{
+				"6900d1a3-798c-4d9a-9a2f-737c72046efa",
+				"0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1",
+			}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			DetectHostConflicts:     true,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, "/0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1", actualIngress.Spec.Rules[0].HTTP.Paths[0].Path)
+
+		hostConflict := getCondition(*actualStatus, networkingv1alpha1.RandomIngressHostConflict)
+		if assert.NotNil(t, hostConflict) {
+			assert.Equal(t, corev1.ConditionFalse, hostConflict.Status)
+			assert.Equal(t, staticHostsSharedReason, hostConflict.Reason)
+		}
+	})
+
+	t.Run("static host in use", func(t *testing.T) {
+		prefix := networkingv1.PathTypePrefix
+
+		randomIngress := testutils.ValidRandomIng.DeepCopy()
+		randomIngress.Spec.IngressTemplate.Spec.Rules = []networkingv1.IngressRule{
+			{
+				Host: "www.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/|RANDOM|", PathType: &prefix}},
+					},
+				},
+			},
+		}
+
+		// Ingresses generated by the RandomIngress itself are not conflicts.
+		ownIngress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "randomIngress-previous",
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(randomIngress, networkingv1alpha1.GroupVersion.WithKind("RandomIngress")),
+				},
+			},
+		}
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient, statusClient := newClientMock(ctrl)
+		updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+		createIngressCall, _ := expectCreateIngress(testClient, nil)
+
+		gomock.InOrder(
+			expectGetRandomIngress(testClient, randomIngress, nil),
+			expectListIngressesByHost(testClient, "www.example.com", []*networkingv1.Ingress{ownIngress, foreignIngress}),
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+			expectListIngressesByHost(testClient, "www.example.com", []*networkingv1.Ingress{ownIngress, foreignIngress}),
+			createIngressCall,
+			updateStatusCall,
+		)
+
+		reconciler := RandomIngressReconciler{
+			Client:                  testClient,
+			Scheme:                  scheme.Scheme,
+			Clock:                   testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+			TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+			IngressMaxLifetime:      testMaxLifetime,
+			IngressHandoverDuration: testGracePeriod,
+			DetectHostConflicts:     true,
+		}
+
+		_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+		assert.NoError(t, err)
+
+		// Sharing a static host is reported, without keeping the condition true.
+		hostConflict := getCondition(*actualStatus, networkingv1alpha1.RandomIngressHostConflict)
+		if assert.NotNil(t, hostConflict) {
+			assert.Equal(t, corev1.ConditionFalse, hostConflict.Status)
+			assert.Equal(t, staticHostsSharedReason, hostConflict.Reason)
+			assert.Equal(t, "static hosts also served by other Ingresses: www.example.com (other-team/their-ingress)", hostConflict.Message)
+		}
+	})
+}
+
+func TestRandomIngressReconciler_TokenHistory(t *testing.T) {
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+	salt := []byte("0123456789abcdef")
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Status.TokenHistory = &networkingv1alpha1.TokenHistory{
+		Salt: hex.EncodeToString(salt),
+		Entries: []networkingv1alpha1.TokenHistoryEntry{
+			{Hash: hashToken(salt, "a1a1a1a1-798c-4d9a-9a2f-737c72046efa"), ExpiresAt: metav1.NewTime(now.Add(-2 * time.Hour))},
+			{Hash: hashToken(salt, "6900d1a3-798c-4d9a-9a2f-737c72046efa"), ExpiresAt: metav1.NewTime(now.Add(-time.Hour))},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil),
+		createIngressCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client: testClient,
+		Scheme: scheme.Scheme,
+		Clock:  testutils.FakeClock{FixedNow: now},
+		TokenSource: testutils.NewFakeTokenSource(t, []string{
+			"6900d1a3-798c-4d9a-9a2f-737c72046efa",
+			"0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1",
+		}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		TokenHistorySize:        2,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	// The first token was issued before, the second one is used instead.
+	assert.Equal(t, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1.example.com", actualIngress.Spec.Rules[0].Host)
+
+	// The oldest entry makes room for the new token, the salt is kept.
+	assert.Equal(t, &networkingv1alpha1.TokenHistory{
+		Salt: hex.EncodeToString(salt),
+		Entries: []networkingv1alpha1.TokenHistoryEntry{
+			{Hash: hashToken(salt, "6900d1a3-798c-4d9a-9a2f-737c72046efa"), ExpiresAt: metav1.NewTime(now.Add(-time.Hour))},
+			{Hash: hashToken(salt, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1"), ExpiresAt: metav1.NewTime(now.Add(testMaxLifetime))},
+		},
+	}, actualStatus.TokenHistory)
+
+	// Live tokens, e.g. derived again within the same epoch, may be issued again.
+	assert.False(t, tokenReused(actualStatus.TokenHistory, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1", now.Add(time.Minute)))
+	assert.True(t, tokenReused(actualStatus.TokenHistory, "0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1", now.Add(testMaxLifetime)))
+}
+
+func TestRandomIngressReconciler_NewTokenHistory(t *testing.T) {
+	reconciler := RandomIngressReconciler{TokenHistorySize: 10}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				issuedAtAnnotation: "2021-09-06T17:12:00Z",
+				tokensAnnotation: `{"":{"value":"6900d1a3-798c-4d9a-9a2f-737c72046efa","issuedAt":"2021-09-06T17:12:00Z","expiresAt":"2021-09-06T17:14:00Z"},` +
+					`"api":{"value":"0b4f4bd0-1ba5-4b0e-a3c1-bb40f7e4c7d1","issuedAt":"2021-09-06T16:12:00Z","expiresAt":"2021-09-06T18:12:00Z"}}`,
+			},
+		},
+	}
+
+	var status networkingv1alpha1.RandomIngressStatus
+	assert.NoError(t, reconciler.recordIssuedTokens(&status, ingress))
+
+	if assert.NotNil(t, status.TokenHistory) {
+		salt, err := hex.DecodeString(status.TokenHistory.Salt)
+		assert.NoError(t, err)
+		assert.Len(t, salt, tokenHistorySaltBytes)
+
+		// The token carried over from a previous Ingress is not recorded again.
+		assert.Equal(t, []networkingv1alpha1.TokenHistoryEntry{
+			{
+				Hash:      hashToken(salt, "6900d1a3-798c-4d9a-9a2f-737c72046efa"),
+				ExpiresAt: metav1.NewTime(time.Date(2021, time.September, 06, 17, 14, 0, 0, time.UTC)),
+			},
+		}, status.TokenHistory.Entries)
+	}
+}
+
+func TestRandomIngressReconciler_TokenHistoryRecordedAgain(t *testing.T) {
+	now := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+	salt := []byte("0123456789abcdef")
+	issuedAt := now.Add(-testMaxLifetime / 2)
+
+	// The status update that followed the creation of the Ingress failed: its token is missing from the history.
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Status.TokenHistory = &networkingv1alpha1.TokenHistory{
+		Salt: hex.EncodeToString(salt),
+		Entries: []networkingv1alpha1.TokenHistoryEntry{
+			{Hash: hashToken(salt, "a1a1a1a1-798c-4d9a-9a2f-737c72046efa"), ExpiresAt: metav1.NewTime(now.Add(-time.Hour))},
+		},
+	}
+
+	existingIngress := testutils.ValidIngress.DeepCopy()
+	existingIngress.CreationTimestamp = metav1.NewTime(issuedAt)
+	existingIngress.Annotations = map[string]string{
+		issuedAtAnnotation: issuedAt.Format(time.RFC3339),
+		tokensAnnotation: fmt.Sprintf(`{"":{"value":%q,"issuedAt":%q,"expiresAt":%q}}`, testutils.ValidIngressUUID,
+			issuedAt.Format(time.RFC3339), issuedAt.Add(testMaxLifetime).Format(time.RFC3339)),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   testutils.FakeClock{FixedNow: now},
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		TokenHistorySize:        10,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	expectedHistory := &networkingv1alpha1.TokenHistory{
+		Salt: hex.EncodeToString(salt),
+		Entries: []networkingv1alpha1.TokenHistoryEntry{
+			{Hash: hashToken(salt, "a1a1a1a1-798c-4d9a-9a2f-737c72046efa"), ExpiresAt: metav1.NewTime(now.Add(-time.Hour))},
+			{Hash: hashToken(salt, testutils.ValidIngressUUID), ExpiresAt: metav1.NewTime(issuedAt.Add(testMaxLifetime))},
+		},
+	}
+	assert.Equal(t, expectedHistory, actualStatus.TokenHistory)
+
+	// Recording the same Ingress again leaves the history as is.
+	assert.NoError(t, reconciler.recordIngressTokens(actualStatus, []client.Object{existingIngress}))
+	assert.Equal(t, expectedHistory, actualStatus.TokenHistory)
+}
+
+func TestRandomIngressReconciler_HTTPRoute(t *testing.T) {
+	clock := testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)}
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+	randomIngress.Spec.HTTPRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{
+		Metadata: networkingv1alpha1.IngressTemplateMetadata{
+			Labels: map[string]string{"team": "checkout"},
+		},
+		Spec: runtime.RawExtension{Raw: []byte(`{"parentRefs":[{"name":"public","namespace":"gateways"}],` +
+			`"hostnames":["|RANDOM|.example.com","www.|RANDOM|.example.com"],"rules":[{"backendRefs":[{"name":"example-service","port":80}]}]}`)},
+	}
+
+	// The Ingress generated before the template was changed to an HTTPRoute is replaced as well.
+	previousIngress := testutils.ValidIngress.DeepCopy()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createRouteCall, actualRoute := expectCreateUnstructured(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{previousIngress}, nil),
+		expectListUnstructured(testClient, httpRouteGVK, "default", "randomIngress", nil),
+		createRouteCall,
+		expectDeleteIngress(testClient, previousIngress, nil),
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		EnableHTTPRoutes:        true,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, httpRouteGVK, actualRoute.GroupVersionKind())
+	assert.True(t, strings.HasPrefix(actualRoute.GetName(), "randomIngress-"))
+	assert.Equal(t, map[string]string{"team": "checkout"}, actualRoute.GetLabels())
+	assert.Equal(t, "2021-09-06T17:12:00Z", actualRoute.GetAnnotations()[issuedAtAnnotation])
+	if assert.Len(t, actualRoute.GetOwnerReferences(), 1) {
+		assert.Equal(t, testutils.ValidRandomIngUID, actualRoute.GetOwnerReferences()[0].UID)
+	}
+
+	hostnames, _, _ := unstructured.NestedStringSlice(actualRoute.Object, "spec", "hostnames")
+	assert.Equal(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", "www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"}, hostnames)
+	parentRefs, _, _ := unstructured.NestedSlice(actualRoute.Object, "spec", "parentRefs")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "public", "namespace": "gateways"}}, parentRefs)
+
+	valid := getCondition(*actualStatus, networkingv1alpha1.RandomIngressValid)
+	if assert.NotNil(t, valid) {
+		assert.Equal(t, corev1.ConditionTrue, valid.Status)
+	}
+
+	if assert.Len(t, actualStatus.ActiveIngresses, 1) {
+		assert.Equal(t, actualRoute.GetName(), actualStatus.ActiveIngresses[0].Name)
+	}
+	assert.Equal(t, hostnames, actualStatus.CurrentHosts)
+
+	// The template must not be modified.
+	assert.Contains(t, string(randomIngress.Spec.HTTPRouteTemplate.Spec.Raw), `"|RANDOM|.example.com"`)
+}
+
+func TestHTTPRouteTarget_ServedBy(t *testing.T) {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "default", "name": "route"},
+		"status": map[string]interface{}{
+			"parents": []interface{}{
+				map[string]interface{}{
+					"parentRef":  map[string]interface{}{"name": "public", "namespace": "gateways"},
+					"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+				},
+				map[string]interface{}{
+					"parentRef":  map[string]interface{}{"name": "internal"},
+					"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "False"}},
+				},
+				map[string]interface{}{
+					"parentRef":  map[string]interface{}{"name": "local"},
+					"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+				},
+			},
+		},
+	}}
+
+	assert.Equal(t, map[string]bool{"gateways/public": true, "default/local": true}, httpRouteTarget{}.servedBy(route))
+}
+
+func TestRandomIngressReconciler_ValidateHTTPRoute(t *testing.T) {
+	testCases := []struct {
+		name            string
+		routeSpec       string
+		ingressTemplate bool
+		disabled        bool
+		expectedMessage string
+	}{
+		{
+			name:      "random hostnames",
+			routeSpec: `{"hostnames":["|RANDOM|.example.com","*.|RANDOM:api|.example.com"]}`,
+		},
+		{
+			name:            "not enabled",
+			routeSpec:       `{"hostnames":["|RANDOM|.example.com"]}`,
+			disabled:        true,
+			expectedMessage: `spec.httpRouteTemplate: Forbidden: HTTPRoutes are not enabled on the operator`,
+		},
+		{
+			name:            "along with an Ingress template",
+			routeSpec:       `{"hostnames":["|RANDOM|.example.com"]}`,
+			ingressTemplate: true,
+			expectedMessage: `spec.ingressTemplate: Forbidden: must be empty when HTTPRoutes are generated instead`,
+		},
+		{
+			name:            "no hostnames",
+			routeSpec:       `{"parentRefs":[{"name":"public"}]}`,
+			expectedMessage: `spec.httpRouteTemplate.spec.hostnames: Required value: routes must be restricted to random hostnames`,
+		},
+		{
+			name:            "invalid hostnames",
+			routeSpec:       `{"hostnames":"|RANDOM|.example.com"}`,
+			expectedMessage: `spec.httpRouteTemplate.spec.hostnames: Invalid value: "|RANDOM|.example.com": must be a list of hostnames`,
+		},
+		{
+			name:            "static hostname",
+			routeSpec:       `{"hostnames":["|RANDOM|.example.com","www.example.com"]}`,
+			expectedMessage: `spec.httpRouteTemplate.spec.hostnames[1]: Invalid value: "www.example.com": missing |RANDOM| placeholder`,
+		},
+		{
+			name:            "duplicate hostname",
+			routeSpec:       `{"hostnames":["|RANDOM|.example.com","|RANDOM|.example.com"]}`,
+			expectedMessage: `spec.httpRouteTemplate.spec.hostnames[1]: Duplicate value: "|RANDOM|.example.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			if !tc.ingressTemplate {
+				spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+			}
+			spec.HTTPRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{Spec: runtime.RawExtension{Raw: []byte(tc.routeSpec)}}
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+				EnableHTTPRoutes:        !tc.disabled,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_OpenShiftRoute(t *testing.T) {
+	clock := testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)}
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+	randomIngress.Spec.OpenShiftRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{
+		Spec: runtime.RawExtension{Raw: []byte(`{"host":"|RANDOM|.apps.example.com",` +
+			`"to":{"kind":"Service","name":"example-service"},"tls":{"termination":"edge"}}`)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createRouteCall, actualRoute := expectCreateUnstructured(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", nil, nil),
+		expectListUnstructured(testClient, openShiftRouteGVK, "default", "randomIngress", nil),
+		createRouteCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		EnableOpenShiftRoutes:   true,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, openShiftRouteGVK, actualRoute.GroupVersionKind())
+	if assert.Len(t, actualRoute.GetOwnerReferences(), 1) {
+		assert.Equal(t, testutils.ValidRandomIngUID, actualRoute.GetOwnerReferences()[0].UID)
+	}
+
+	host, _, _ := unstructured.NestedString(actualRoute.Object, "spec", "host")
+	assert.Equal(t, "6900d1a3-798c-4d9a-9a2f-737c72046efa.apps.example.com", host)
+	termination, _, _ := unstructured.NestedString(actualRoute.Object, "spec", "tls", "termination")
+	assert.Equal(t, "edge", termination)
+
+	assert.Equal(t, []string{host}, actualStatus.CurrentHosts)
+}
+
+func TestOpenShiftRouteTarget_ServedBy(t *testing.T) {
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"ingress": []interface{}{
+				map[string]interface{}{
+					"routerName": "default",
+					"conditions": []interface{}{map[string]interface{}{"type": "Admitted", "status": "True"}},
+				},
+				map[string]interface{}{
+					"routerName": "internal",
+					"conditions": []interface{}{map[string]interface{}{"type": "Admitted", "status": "False"}},
+				},
+				map[string]interface{}{
+					"routerName": "sharded",
+				},
+			},
+		},
+	}}
+
+	assert.Equal(t, map[string]bool{"default": true}, openShiftRouteTarget{}.servedBy(route))
+}
+
+func TestRandomIngressReconciler_ValidateOpenShiftRoute(t *testing.T) {
+	testCases := []struct {
+		name            string
+		routeSpec       string
+		httpRoute       bool
+		disabled        bool
+		expectedMessage string
+	}{
+		{
+			name:      "random host",
+			routeSpec: `{"host":"|RANDOM|.apps.example.com","to":{"kind":"Service","name":"example-service"}}`,
+		},
+		{
+			name:            "not enabled",
+			routeSpec:       `{"host":"|RANDOM|.apps.example.com"}`,
+			disabled:        true,
+			expectedMessage: `spec.openShiftRouteTemplate: Forbidden: Routes are not enabled on the operator`,
+		},
+		{
+			name:            "along with an HTTPRoute template",
+			routeSpec:       `{"host":"|RANDOM|.apps.example.com"}`,
+			httpRoute:       true,
+			expectedMessage: `spec.openShiftRouteTemplate: Forbidden: cannot be set along with httpRouteTemplate`,
+		},
+		{
+			name:            "no host",
+			routeSpec:       `{"to":{"kind":"Service","name":"example-service"}}`,
+			expectedMessage: `spec.openShiftRouteTemplate.spec.host: Required value: routes must be restricted to a random host`,
+		},
+		{
+			name:            "static host",
+			routeSpec:       `{"host":"www.apps.example.com"}`,
+			expectedMessage: `spec.openShiftRouteTemplate.spec.host: Invalid value: "www.apps.example.com": missing |RANDOM| placeholder`,
+		},
+		{
+			name:            "invalid host",
+			routeSpec:       `{"host":"|RANDOM|_.apps.example.com"}`,
+			expectedMessage: `spec.openShiftRouteTemplate.spec.host: Invalid value: "|RANDOM|_.apps.example.com": `,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+			spec.OpenShiftRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{Spec: runtime.RawExtension{Raw: []byte(tc.routeSpec)}}
+			if tc.httpRoute {
+				spec.HTTPRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{
+					Spec: runtime.RawExtension{Raw: []byte(`{"hostnames":["|RANDOM|.example.com"]}`)},
+				}
+			}
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+				EnableHTTPRoutes:        true,
+				EnableOpenShiftRoutes:   !tc.disabled,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else if assert.Error(t, errs.ToAggregate()) {
+				assert.True(t, strings.HasPrefix(errs.ToAggregate().Error(), tc.expectedMessage), errs.ToAggregate().Error())
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_VirtualService(t *testing.T) {
+	clock := testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)}
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+	randomIngress.Spec.VirtualServiceTemplate = &networkingv1alpha1.VirtualServiceTemplateSpec{
+		ObjectTemplateSpec: networkingv1alpha1.ObjectTemplateSpec{
+			Spec: runtime.RawExtension{Raw: []byte(`{"hosts":["|RANDOM|.example.com"],"gateways":["public"],` +
+				`"http":[{"route":[{"destination":{"host":"example-service"}}]}]}`)},
+		},
+		SyncGateway: &networkingv1alpha1.GatewaySync{Name: "public", Servers: []string{"https"}},
+	}
+	// Hosts of the previous VirtualServices, already deleted, are removed from every server.
+	randomIngress.Status.GatewayHosts = &networkingv1alpha1.GatewayHosts{Name: "public", Hosts: []string{"old.example.com"}}
+
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "default", "name": "public"},
+		"spec": map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "https", "hosts": []interface{}{"static.example.com", "old.example.com"}},
+				map[string]interface{}{"name": "http", "hosts": []interface{}{"redirect.example.com", "old.example.com"}},
+			},
+		},
+	}}
+	gateway.SetGroupVersionKind(istioGatewayGVK)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createServiceCall, actualService := expectCreateUnstructured(testClient, nil)
+	updateGatewayCall, actualGateway := expectUpdateUnstructured(testClient, nil)
+	addFinalizerCall, withFinalizer := expectUpdateRandomIngress(testClient)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		addFinalizerCall,
+		expectListIngresses(testClient, "default", "randomIngress", nil, nil),
+		expectListUnstructured(testClient, virtualServiceGVK, "default", "randomIngress", nil),
+		createServiceCall,
+		expectGetUnstructured(testClient, gateway),
+		updateGatewayCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		EnableVirtualServices:   true,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, virtualServiceGVK, actualService.GroupVersionKind())
+	hosts, _, _ := unstructured.NestedStringSlice(actualService.Object, "spec", "hosts")
+	assert.Equal(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"}, hosts)
+
+	servers, _, _ := unstructured.NestedSlice(actualGateway.Object, "spec", "servers")
+	if assert.Len(t, servers, 2) {
+		assert.Equal(t, []interface{}{"static.example.com", "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"}, servers[0].(map[string]interface{})["hosts"])
+		assert.Equal(t, []interface{}{"redirect.example.com"}, servers[1].(map[string]interface{})["hosts"])
+	}
+
+	assert.Equal(t, &networkingv1alpha1.GatewayHosts{Name: "public", Hosts: hosts}, actualStatus.GatewayHosts)
+
+	// The finalizer is added before any host is added to the Gateway.
+	assert.Equal(t, []string{gatewayHostsFinalizer}, withFinalizer.Finalizers)
+}
+
+func TestRandomIngressReconciler_VirtualServiceDeletion(t *testing.T) {
+	clock := testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)}
+	deletedAt := metav1.NewTime(clock.FixedNow)
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.DeletionTimestamp = &deletedAt
+	randomIngress.Finalizers = []string{gatewayHostsFinalizer}
+	randomIngress.Spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+	randomIngress.Spec.VirtualServiceTemplate = &networkingv1alpha1.VirtualServiceTemplateSpec{
+		ObjectTemplateSpec: networkingv1alpha1.ObjectTemplateSpec{
+			Spec: runtime.RawExtension{Raw: []byte(`{"hosts":["|RANDOM|.example.com"],"gateways":["public"]}`)},
+		},
+		SyncGateway: &networkingv1alpha1.GatewaySync{Name: "public"},
+	}
+	randomIngress.Status.GatewayHosts = &networkingv1alpha1.GatewayHosts{
+		Name:  "public",
+		Hosts: []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"},
+	}
+
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "default", "name": "public"},
+		"spec": map[string]interface{}{
+			"servers": []interface{}{
+				map[string]interface{}{"name": "https", "hosts": []interface{}{"static.example.com", "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"}},
+			},
+		},
+	}}
+	gateway.SetGroupVersionKind(istioGatewayGVK)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, _ := newClientMock(ctrl)
+	updateGatewayCall, actualGateway := expectUpdateUnstructured(testClient, nil)
+	removeFinalizerCall, withoutFinalizer := expectUpdateRandomIngress(testClient)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectGetUnstructured(testClient, gateway),
+		updateGatewayCall,
+		removeFinalizerCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+		EnableVirtualServices:   true,
+	}
+
+	result, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+
+	servers, _, _ := unstructured.NestedSlice(actualGateway.Object, "spec", "servers")
+	if assert.Len(t, servers, 1) {
+		assert.Equal(t, []interface{}{"static.example.com"}, servers[0].(map[string]interface{})["hosts"])
+	}
+
+	assert.Empty(t, withoutFinalizer.Finalizers)
+}
+
+func TestRandomIngressReconciler_RemoveLastGatewayHosts(t *testing.T) {
+	const generatedHost = "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"
+
+	newGateway := func(servers ...interface{}) *unstructured.Unstructured {
+		gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"namespace": "default", "name": "public"},
+			"spec":     map[string]interface{}{"servers": servers},
+		}}
+		gateway.SetGroupVersionKind(istioGatewayGVK)
+		return gateway
+	}
+
+	t.Run("server left without hosts removed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient := mock_client.NewMockClient(ctrl)
+		updateGatewayCall, actualGateway := expectUpdateUnstructured(testClient, nil)
+		gomock.InOrder(
+			expectGetUnstructured(testClient, newGateway(
+				map[string]interface{}{"name": "random", "hosts": []interface{}{generatedHost}},
+				map[string]interface{}{"name": "static", "hosts": []interface{}{"static.example.com"}},
+			)),
+			updateGatewayCall,
+		)
+
+		reconciler := RandomIngressReconciler{Client: testClient}
+		assert.NoError(t, reconciler.updateGatewayHosts(context.Background(), "default", "public", nil, []string{generatedHost}, nil))
+
+		servers, _, _ := unstructured.NestedSlice(actualGateway.Object, "spec", "servers")
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"name": "static", "hosts": []interface{}{"static.example.com"}},
+		}, servers)
+	})
+
+	t.Run("no server left", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testClient := mock_client.NewMockClient(ctrl)
+		expectGetUnstructured(testClient, newGateway(
+			map[string]interface{}{"name": "random", "hosts": []interface{}{generatedHost}},
+		))
+
+		reconciler := RandomIngressReconciler{Client: testClient}
+		err := reconciler.updateGatewayHosts(context.Background(), "default", "public", nil, []string{generatedHost}, nil)
+		assert.EqualError(t, err, "removing hosts would leave Gateway public without servers, it must be deleted or serve other hosts")
+	})
+}
+
+func TestVirtualServiceTarget_ServedBy(t *testing.T) {
+	service := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "default", "name": "service"},
+		"spec":     map[string]interface{}{"gateways": []interface{}{"public", "istio-system/shared", "mesh"}},
+	}}
+
+	assert.Empty(t, virtualServiceTarget{}.servedBy(service))
+
+	service.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Reconciled", "status": "True"}},
+	}
+	assert.Equal(t, map[string]bool{"default/public": true, "istio-system/shared": true, "mesh": true}, virtualServiceTarget{}.servedBy(service))
+}
+
+func TestRandomIngressReconciler_ValidateVirtualService(t *testing.T) {
+	testCases := []struct {
+		name            string
+		serviceSpec     string
+		syncGateway     string
+		openShiftRoute  bool
+		expectedMessage string
+	}{
+		{
+			name:        "random hosts",
+			serviceSpec: `{"hosts":["|RANDOM|.example.com"],"gateways":["default/public"]}`,
+			syncGateway: "public",
+		},
+		{
+			name:            "along with an OpenShift Route template",
+			serviceSpec:     `{"hosts":["|RANDOM|.example.com"]}`,
+			openShiftRoute:  true,
+			expectedMessage: `[spec.ingressTemplate: Forbidden: must be empty when Routes are generated instead, spec.virtualServiceTemplate: Forbidden: cannot be set along with openShiftRouteTemplate]`,
+		},
+		{
+			name:            "no hosts",
+			serviceSpec:     `{"gateways":["public"]}`,
+			expectedMessage: `spec.virtualServiceTemplate.spec.hosts: Required value: virtual services must be restricted to random hosts`,
+		},
+		{
+			name:            "static host",
+			serviceSpec:     `{"hosts":["|RANDOM|.example.com","example-service"]}`,
+			expectedMessage: `spec.virtualServiceTemplate.spec.hosts[1]: Invalid value: "example-service": missing |RANDOM| placeholder`,
+		},
+		{
+			name:            "not bound to the synced gateway",
+			serviceSpec:     `{"hosts":["|RANDOM|.example.com"],"gateways":["internal"]}`,
+			syncGateway:     "public",
+			expectedMessage: `spec.virtualServiceTemplate.spec.gateways: Invalid value: []string{"internal"}: must include the synced gateway public`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+			spec.VirtualServiceTemplate = &networkingv1alpha1.VirtualServiceTemplateSpec{
+				ObjectTemplateSpec: networkingv1alpha1.ObjectTemplateSpec{Spec: runtime.RawExtension{Raw: []byte(tc.serviceSpec)}},
+			}
+			if tc.syncGateway != "" {
+				spec.VirtualServiceTemplate.SyncGateway = &networkingv1alpha1.GatewaySync{Name: tc.syncGateway}
+			}
+			if tc.openShiftRoute {
+				spec.IngressTemplate = testutils.ValidRandomIng.Spec.IngressTemplate
+				spec.OpenShiftRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{
+					Spec: runtime.RawExtension{Raw: []byte(`{"host":"|RANDOM|.apps.example.com"}`)},
+				}
+			}
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:      8 * time.Hour,
+				IngressHandoverDuration: 10 * time.Minute,
+				MinTokenEntropyBits:     122,
+				EnableOpenShiftRoutes:   true,
+				EnableVirtualServices:   true,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_TraefikIngressRoute(t *testing.T) {
+	clock := testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)}
+
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+	randomIngress.Spec.TraefikIngressRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{
+		Spec: runtime.RawExtension{Raw: []byte(`{"entryPoints":["websecure"],"routes":[` +
+			"{\"kind\":\"Rule\",\"match\":\"Host(`|RANDOM|.example.com`, `www.|RANDOM|.example.com`) && PathPrefix(`/api`)\"," +
+			`"services":[{"name":"example-service","port":80}]}],` +
+			`"tls":{"domains":[{"main":"|RANDOM|.example.com","sans":["www.|RANDOM|.example.com"]}]}}`)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createRouteCall, actualRoute := expectCreateUnstructured(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", nil, nil),
+		expectListUnstructured(testClient, traefikIngressRouteGVK, "default", "randomIngress", nil),
+		createRouteCall,
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                     testClient,
+		Scheme:                     scheme.Scheme,
+		Clock:                      clock,
+		TokenSource:                testutils.NewFakeTokenSource(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa"}),
+		IngressMaxLifetime:         testMaxLifetime,
+		IngressHandoverDuration:    testGracePeriod,
+		EnableTraefikIngressRoutes: true,
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, traefikIngressRouteGVK, actualRoute.GroupVersionKind())
+
+	routes, _, _ := unstructured.NestedSlice(actualRoute.Object, "spec", "routes")
+	if assert.Len(t, routes, 1) {
+		assert.Equal(t, "Host(`6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com`, `www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com`) && PathPrefix(`/api`)",
+			routes[0].(map[string]interface{})["match"])
+	}
+
+	domains, _, _ := unstructured.NestedSlice(actualRoute.Object, "spec", "tls", "domains")
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"main": "6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com",
+		"sans": []interface{}{"www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"},
+	}}, domains)
+
+	assert.Equal(t, []string{"6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com", "www.6900d1a3-798c-4d9a-9a2f-737c72046efa.example.com"},
+		actualStatus.CurrentHosts)
+}
+
+func TestMatchedHosts(t *testing.T) {
+	assert.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com"},
+		matchedHosts("(Host(`a.example.com`, `b.example.com`) || Host(\"c.example.com\")) && HostSNI(`d.example.com`)"))
+	assert.Empty(t, matchedHosts("PathPrefix(`/|RANDOM|`)"))
+}
+
+func TestRandomIngressReconciler_ValidateTraefikIngressRoute(t *testing.T) {
+	testCases := []struct {
+		name            string
+		routeSpec       string
+		readinessGate   bool
+		expectedMessage string
+	}{
+		{
+			name: "random matches",
+			routeSpec: "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`)\"},{\"match\":\"Host(`|RANDOM|.example.com`) && PathPrefix(`/api`)\"}]," +
+				"\"tls\":{\"domains\":[{\"main\":\"*.example.com\",\"sans\":[\"|RANDOM|.example.com\"]}]}}",
+		},
+		{
+			name:            "readiness gate",
+			routeSpec:       "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`)\"}]}",
+			readinessGate:   true,
+			expectedMessage: `spec.readinessGate: Forbidden: IngressRoutes report no status to wait for`,
+		},
+		{
+			name:            "no routes",
+			routeSpec:       `{"entryPoints":["websecure"]}`,
+			expectedMessage: `spec.traefikIngressRouteTemplate.spec.routes: Required value: at least one route is required`,
+		},
+		{
+			name:            "static match",
+			routeSpec:       "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`)\"},{\"match\":\"Host(`www.example.com`)\"}]}",
+			expectedMessage: "spec.traefikIngressRouteTemplate.spec.routes[1].match: Invalid value: \"Host(`www.example.com`)\": host www.example.com: missing |RANDOM| placeholder",
+		},
+		{
+			name:            "static host along with a random one",
+			routeSpec:       "{\"routes\":[{\"match\":\"Host(`a.|RANDOM|.example.com`, `public.example.com`)\"}]}",
+			expectedMessage: "spec.traefikIngressRouteTemplate.spec.routes[0].match: Invalid value: \"Host(`a.|RANDOM|.example.com`, `public.example.com`)\": host public.example.com: missing |RANDOM| placeholder",
+		},
+		{
+			name:      "static host in a disjunction",
+			routeSpec: "{\"routes\":[{\"match\":\"Host(`a.|RANDOM|.example.com`) || Host(`public.example.com`)\"}]}",
+			expectedMessage: "[spec.traefikIngressRouteTemplate.spec.routes[0].match: Invalid value: \"Host(`a.|RANDOM|.example.com`) || Host(`public.example.com`)\": host public.example.com: missing |RANDOM| placeholder, " +
+				"spec.traefikIngressRouteTemplate.spec.routes[0].match: Invalid value: \"Host(`a.|RANDOM|.example.com`) || Host(`public.example.com`)\": must be restricted to random hosts by a Host matcher, combined with other matchers by && only]",
+		},
+		{
+			name:            "random path in a disjunction",
+			routeSpec:       "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`) || PathPrefix(`/api`)\"}]}",
+			expectedMessage: "spec.traefikIngressRouteTemplate.spec.routes[0].match: Invalid value: \"Host(`|RANDOM|.example.com`) || PathPrefix(`/api`)\": must be restricted to random hosts by a Host matcher, combined with other matchers by && only",
+		},
+		{
+			name:            "random path without host",
+			routeSpec:       "{\"routes\":[{\"match\":\"PathPrefix(`/|RANDOM|`)\"}]}",
+			expectedMessage: "spec.traefikIngressRouteTemplate.spec.routes[0].match: Invalid value: \"PathPrefix(`/|RANDOM|`)\": must be restricted to random hosts by a Host matcher, combined with other matchers by && only",
+		},
+		{
+			name:      "disjunction within a conjunction",
+			routeSpec: "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`) && (Path(`/a`) || Path(`/b`))\"}]}",
+		},
+		{
+			name: "static TLS domains",
+			routeSpec: "{\"routes\":[{\"match\":\"Host(`|RANDOM|.example.com`)\"}]," +
+				"\"tls\":{\"domains\":[{\"main\":\"public.example.com\",\"sans\":[\"|RANDOM|.example.com\",\"*.other.example.com\"]}]}}",
+			expectedMessage: `[spec.traefikIngressRouteTemplate.spec.tls.domains[0].main: Invalid value: "public.example.com": does not match any route host, ` +
+				`spec.traefikIngressRouteTemplate.spec.tls.domains[0].sans[1]: Invalid value: "*.other.example.com": does not match any route host]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.IngressTemplate = networkingv1alpha1.IngressTemplateSpec{}
+			spec.TraefikIngressRouteTemplate = &networkingv1alpha1.ObjectTemplateSpec{Spec: runtime.RawExtension{Raw: []byte(tc.routeSpec)}}
+			if tc.readinessGate {
+				spec.ReadinessGate = &networkingv1alpha1.ReadinessGate{}
+			}
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:         8 * time.Hour,
+				IngressHandoverDuration:    10 * time.Minute,
+				ReadinessGateMaxExtension:  time.Hour,
+				MinTokenEntropyBits:        122,
+				EnableTraefikIngressRoutes: true,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ValidateToken(t *testing.T) {
+
+	testCases := []struct {
+		name                string
+		token               *networkingv1alpha1.TokenSpec
+		minTokenEntropyBits int
+		expectedMessage     string
+	}{
+		{
+			name:                "default uuidv4",
+			minTokenEntropyBits: 122,
+		},
+		{
+			name:                "uuidv4 below the operator minimum",
+			minTokenEntropyBits: 128,
+			expectedMessage:     `spec.token.format: Invalid value: "uuidv4": gives 122 bits of entropy, must give at least 128`,
+		},
+		{
+			name:            "entropy set for uuidv4",
+			token:           &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatUUIDv4, EntropyBits: testutils.Int32Ptr(128)},
+			expectedMessage: "spec.token.entropyBits: Invalid value: 128: must not be set for uuidv4 tokens",
+		},
+		{
+			name:                "default entropy",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatBase32},
+			minTokenEntropyBits: 128,
+		},
+		{
+			name:                "entropy below the operator minimum",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatHex, EntropyBits: testutils.Int32Ptr(64)},
+			minTokenEntropyBits: 122,
+			expectedMessage:     "spec.token.entropyBits: Invalid value: 64: gives 64 bits of entropy, must give at least 122",
+		},
+		{
+			name:            "negative entropy",
+			token:           &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatHex, EntropyBits: testutils.Int32Ptr(-1)},
+			expectedMessage: "spec.token.entropyBits: Invalid value: -1: must be positive",
+		},
+		{
+			name:                "default words",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords},
+			minTokenEntropyBits: 122,
+		},
+		{
+			name:                "words without separator",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords, WordCount: testutils.Int32Ptr(7), Separator: testutils.StringPtr("")},
+			minTokenEntropyBits: 122,
+		},
+		{
+			name:                "too few words",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords, WordCount: testutils.Int32Ptr(4)},
+			minTokenEntropyBits: 122,
+			expectedMessage:     "spec.token.wordCount: Invalid value: 4: gives 51 bits of entropy, must give at least 64",
+		},
+		{
+			name:                "too many words",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords, WordCount: testutils.Int32Ptr(7)},
+			minTokenEntropyBits: 122,
+			expectedMessage:     "spec.token.wordCount: Invalid value: 7: must fit in a DNS label of 63 characters",
+		},
+		{
+			name:                "words entropy longer than a DNS label",
+			token:               &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords, EntropyBits: testutils.Int32Ptr(122)},
+			minTokenEntropyBits: 122,
+			expectedMessage:     "spec.token.entropyBits: Invalid value: 122: must fit in a DNS label of 63 characters",
+		},
+		{
+			name:            "word count and entropy",
+			token:           &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatWords, WordCount: testutils.Int32Ptr(4), EntropyBits: testutils.Int32Ptr(44)},
+			expectedMessage: "spec.token.entropyBits: Invalid value: 44: must not be set along with wordCount",
+		},
+		{
+			name:            "separator without words",
+			token:           &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatHex, Separator: testutils.StringPtr("-")},
+			expectedMessage: `spec.token.separator: Invalid value: "-": must only be set for words tokens`,
+		},
+		{
+			name:            "longer than a DNS label",
+			token:           &networkingv1alpha1.TokenSpec{Format: networkingv1alpha1.TokenFormatHex, EntropyBits: testutils.Int32Ptr(256)},
+			expectedMessage: "spec.token.entropyBits: Invalid value: 256: must fit in a DNS label of 63 characters",
+		},
+		{
+			name: "derived without secret name",
+			token: &networkingv1alpha1.TokenSpec{
+				DerivedFrom: &networkingv1alpha1.TokenDerivation{SecretKeyRef: corev1.SecretKeySelector{Key: "key"}},
+			},
+			minTokenEntropyBits: 122,
+			expectedMessage:     "spec.token.derivedFrom.secretKeyRef.name: Required value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.Token = tc.token
+
+			reconciler := RandomIngressReconciler{
+				Clock: testutils.FakeClock{
+					FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+				},
+				IngressMaxLifetime:       8 * time.Hour,
+				IngressHandoverDuration:  10 * time.Minute,
+				MinTokenEntropyBits:      tc.minTokenEntropyBits,
+				MinWordsTokenEntropyBits: 64,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			if tc.expectedMessage == "" {
+				assert.Empty(t, errs)
+			} else {
+				assert.EqualError(t, errs.ToAggregate(), tc.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestRandomIngressReconciler_ExpiryFromAnnotations(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+
+	expectedToken := "3d3ff6d6-4b54-4d0c-8f43-ae0d8d4f8b0b"
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{expectedToken})
+
+	// The Ingress was just restored from a backup: its creation timestamp is recent,
+	// but the annotations tell that it expired a second ago.
+	existingIngress := testutils.ValidIngress.DeepCopy()
+	existingIngress.CreationTimestamp = metav1.NewTime(clock.FixedNow)
+	existingIngress.Annotations = map[string]string{
+		issuedAtAnnotation:  clock.FixedNow.Add(-testMaxLifetime).Add(-time.Second).Format(time.RFC3339),
+		expiresAtAnnotation: clock.FixedNow.Add(-time.Second).Format(time.RFC3339),
+	}
+
+	expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, clock.FixedNow.Add(testMaxLifetime))
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+	createIngressCall, actualIngress := expectCreateIngress(testClient, nil)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+		createIngressCall,
+		expectDeleteIngress(testClient, existingIngress, nil),
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testTokenSource,
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+	assert.Equal(t, testMaxLifetime-testGracePeriod, res.RequeueAfter)
+
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(actualIngress, clock.FixedNow, clock.FixedNow.Add(testMaxLifetime), networkingv1alpha1.ActiveIngressActive))
+	expectedStatus.LastIngressDeletion = newExpectedIngressDeletion(existingIngress, clock.FixedNow.Add(-time.Second), clock.FixedNow)
+
+	assertStatusEquivalent(t, expectedStatus, actualStatus)
+	assertIngressMatchesTemplate(t, randomIngress, actualIngress, expectedToken)
+}
+
+func TestIngressExpiresAt(t *testing.T) {
+	issuedAt := time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    time.Time
+	}{
+		{
+			name:     "no annotation falls back to creation timestamp",
+			expected: issuedAt.Add(-time.Hour).Add(testMaxLifetime),
+		},
+		{
+			name: "stamped expiration",
+			annotations: map[string]string{
+				issuedAtAnnotation:  issuedAt.Format(time.RFC3339),
+				expiresAtAnnotation: issuedAt.Add(time.Minute).Format(time.RFC3339),
+			},
+			expected: issuedAt.Add(time.Minute),
+		},
+		{
+			name: "lifetime reduced since the expiration was stamped",
+			annotations: map[string]string{
+				issuedAtAnnotation:  issuedAt.Format(time.RFC3339),
+				expiresAtAnnotation: issuedAt.Add(time.Hour).Format(time.RFC3339),
+			},
+			expected: issuedAt.Add(testMaxLifetime),
+		},
+		{
+			name: "malformed annotations",
+			annotations: map[string]string{
+				issuedAtAnnotation:  "yesterday",
+				expiresAtAnnotation: "tomorrow",
+			},
+			expected: issuedAt.Add(-time.Hour).Add(testMaxLifetime),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ingress := testutils.ValidIngress.DeepCopy()
+			ingress.CreationTimestamp = metav1.NewTime(issuedAt.Add(-time.Hour))
+			ingress.Annotations = tc.annotations
+
+			actual := ingressExpiresAt(ingress, rotationSettings{maxLifetime: testMaxLifetime, handoverDuration: testGracePeriod})
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRandomIngressReconciler_SpecLifetimeOverride(t *testing.T) {
+	randomIngress := testutils.ValidRandomIng.DeepCopy()
+	randomIngress.Spec.MaxLifetime = &metav1.Duration{Duration: 10 * time.Minute}
+	randomIngress.Spec.HandoverDuration = &metav1.Duration{Duration: time.Minute}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clock := testutils.FakeClock{
+		FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+	}
+
+	testTokenSource := testutils.NewFakeTokenSource(t, []string{})
+
+	testClient, statusClient := newClientMock(ctrl)
+	updateStatusCall, actualStatus := expectUpdateStatus(statusClient, nil)
+
+	// Older than the operator-wide lifetime, but still young for the lifetime requested in the spec.
+	existingCreationTimestamp := clock.FixedNow.Add(-3 * time.Minute)
+
+	expectedNextRenewalTime := existingCreationTimestamp.Add(10 * time.Minute)
+	expectedRequeueAfter := 10*time.Minute - 3*time.Minute - time.Minute
+
+	expectedStatus := testutils.NewValidRandomIngStatus(clock.FixedNow, expectedNextRenewalTime)
+
+	existingIngress := testutils.ValidIngress.DeepCopy()
+	existingIngress.CreationTimestamp = metav1.NewTime(existingCreationTimestamp)
+
+	gomock.InOrder(
+		expectGetRandomIngress(testClient, randomIngress, nil),
+		expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{existingIngress}, nil),
+		updateStatusCall,
+	)
+
+	reconciler := RandomIngressReconciler{
+		Client:                  testClient,
+		Scheme:                  scheme.Scheme,
+		Clock:                   clock,
+		TokenSource:             testTokenSource,
+		IngressMaxLifetime:      testMaxLifetime,
+		IngressHandoverDuration: testGracePeriod,
+	}
+
+	res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRequeueAfter, res.RequeueAfter)
+
+	setExpectedActiveIngresses(expectedStatus,
+		newExpectedActiveIngress(existingIngress, existingCreationTimestamp, expectedNextRenewalTime, networkingv1alpha1.ActiveIngressActive))
+
+	assertStatusEquivalent(t, expectedStatus, actualStatus)
+}
+
+func TestRandomIngressReconciler_NonPositiveLifetimeWithoutLowerBound(t *testing.T) {
+	for _, maxLifetime := range []time.Duration{0, -time.Hour} {
+		t.Run(maxLifetime.String(), func(t *testing.T) {
+			spec := testutils.ValidRandomIng.Spec.DeepCopy()
+			spec.MaxLifetime = &metav1.Duration{Duration: maxLifetime}
+			spec.Token = &networkingv1alpha1.TokenSpec{
+				DerivedFrom: &networkingv1alpha1.TokenDerivation{
+					SecretKeyRef: corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+						Key:                  "key",
+					},
+				},
+			}
+
+			reconciler := RandomIngressReconciler{
+				Clock:                   testutils.FakeClock{FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC)},
+				IngressMaxLifetime:      testMaxLifetime,
+				IngressHandoverDuration: testGracePeriod,
+				MinTokenEntropyBits:     122,
+			}
+
+			errs := reconciler.validateSpec(spec)
+			assert.Contains(t, errs.ToAggregate().Error(),
+				fmt.Sprintf(`spec.maxLifetime: Invalid value: "%s": must be positive`, maxLifetime))
+
+			// Derived tokens must not divide by the lifetime meanwhile.
+			settings := reconciler.rotationSettings(spec)
+			assert.NotPanics(t, func() { settings.epoch(reconciler.Clock.Now()) })
+		})
+	}
+}
+
+func TestRandomIngressReconciler_InvalidLifetime(t *testing.T) {
+	testCases := []struct {
+		name             string
+		maxLifetime      *metav1.Duration
+		handoverDuration *metav1.Duration
+		expectedMessage  string
+	}{
+		{
+			name:            "lifetime below lower bound",
+			maxLifetime:     &metav1.Duration{Duration: time.Minute},
+			expectedMessage: `spec.maxLifetime: Invalid value: "1m0s": must be at least 1h0m0s`,
+		},
+		{
+			name:            "lifetime above upper bound",
+			maxLifetime:     &metav1.Duration{Duration: 48 * time.Hour},
+			expectedMessage: `spec.maxLifetime: Invalid value: "48h0m0s": must be at most 24h0m0s`,
+		},
+		{
+			name:        "zero lifetime",
+			maxLifetime: &metav1.Duration{Duration: 0},
+			expectedMessage: `[spec.maxLifetime: Invalid value: "0s": must be positive, ` +
+				`spec.handoverDuration: Invalid value: "10s": must not be negative and must be shorter than the max lifetime (0s)]`,
+		},
+		{
+			name:        "negative lifetime",
+			maxLifetime: &metav1.Duration{Duration: -time.Hour},
+			expectedMessage: `[spec.maxLifetime: Invalid value: "-1h0m0s": must be positive, ` +
+				`spec.handoverDuration: Invalid value: "10s": must not be negative and must be shorter than the max lifetime (-1h0m0s)]`,
+		},
+		{
+			name:             "handover longer than lifetime",
+			maxLifetime:      &metav1.Duration{Duration: 2 * time.Hour},
+			handoverDuration: &metav1.Duration{Duration: 3 * time.Hour},
+			expectedMessage:  `spec.handoverDuration: Invalid value: "3h0m0s": must not be negative and must be shorter than the max lifetime (2h0m0s)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			randomIngress := testutils.ValidRandomIng.DeepCopy()
+			randomIngress.Spec.MaxLifetime = tc.maxLifetime
+			randomIngress.Spec.HandoverDuration = tc.handoverDuration
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			testClient, statusClient := newClientMock(ctrl)
+			expectGetRandomIngress(testClient, randomIngress, nil)
+			expectListIngresses(testClient, "default", "randomIngress", []*networkingv1.Ingress{}, nil)
+			_, actualStatus := expectUpdateStatus(statusClient, nil)
+
+			clock := testutils.FakeClock{
+				FixedNow: time.Date(2021, time.September, 06, 17, 12, 0, 0, time.UTC),
+			}
+
+			reconciler := RandomIngressReconciler{
+				Client:                    testClient,
+				Scheme:                    scheme.Scheme,
+				Clock:                     clock,
+				TokenSource:               testutils.NewFakeTokenSource(t, []string{}),
+				IngressMaxLifetime:        testMaxLifetime,
+				IngressHandoverDuration:   testGracePeriod,
+				IngressLifetimeLowerBound: time.Hour,
+				IngressLifetimeUpperBound: 24 * time.Hour,
+			}
+
+			res, err := reconciler.Reconcile(context.Background(), newReq("default", "randomIngress"))
+			assert.Nil(t, err)
+			assert.Zero(t, res)
+
+			expectedStatus := &networkingv1alpha1.RandomIngressStatus{
+				Conditions: []networkingv1alpha1.RandomIngressCondition{
+					{
+						Type:               networkingv1alpha1.RandomIngressValid,
+						Status:             corev1.ConditionFalse,
+						Reason:             specInvalidReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            tc.expectedMessage,
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressProgressing,
+						Status:             corev1.ConditionFalse,
+						Reason:             noIngressLiveReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no Ingress is live",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressSuspended,
+						Status:             corev1.ConditionFalse,
+						Reason:             rotationActiveReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "rotation is active",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressRotationDeferred,
+						Status:             corev1.ConditionFalse,
+						Reason:             notDeferredReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "rotation is not deferred",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressReplacementFailed,
+						Status:             corev1.ConditionFalse,
+						Reason:             noReplacementFailureReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no replacement failed",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressPerHostCertificateRequired,
+						Status:             corev1.ConditionFalse,
+						Reason:             noRandomTLSHostsReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no TLS host needs a per-host certificate",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressHostConflict,
+						Status:             corev1.ConditionFalse,
+						Reason:             hostConflictDetectionOffReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "host conflict detection is disabled",
+					},
+					{
+						Type:               networkingv1alpha1.RandomIngressNewIngressNotReady,
+						Status:             corev1.ConditionFalse,
+						Reason:             readinessGateDisabledReason,
+						LastHeartbeatTime:  metav1.Time{Time: clock.FixedNow},
+						LastTransitionTime: metav1.Time{Time: clock.FixedNow},
+						Message:            "no readiness gate is configured",
+					},
+				},
+
+				TokenEntropyBits: 122,
+			}
+
+			assertStatusEquivalent(t, expectedStatus, actualStatus)
+		})
+	}
+}
+
+func newReq(namespace, name string) reconcile.Request {
+	return reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+// newClientMock creates a mock of Client with the Status sub-client also created, and returns both.
+func newClientMock(ctrl *gomock.Controller) (*mock_client.MockClient, *mock_client.MockStatusWriter) {
+	statusClient := mock_client.NewMockStatusWriter(ctrl)
+	client := mock_client.NewMockClient(ctrl)
+	client.EXPECT().Status().AnyTimes().Return(statusClient)
+
+	return client, statusClient
+}
+
+func expectGetRandomIngress(mock *mock_client.MockClient, expectedOutput *networkingv1alpha1.RandomIngress, expectedErr error) *gomock.Call {
+	key := client.ObjectKey{
+		Namespace: expectedOutput.Namespace,
+		Name:      expectedOutput.Name,
+	}
+
+	call := mock.EXPECT().Get(gomock.Not(gomock.Nil()), key, gomock.AssignableToTypeOf(expectedOutput)).
+		DoAndReturn(func(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...interface{}) error {
+			if expectedOutput != nil {
+				outObj := obj.(*networkingv1alpha1.RandomIngress)
+				expectedOutput.DeepCopyInto(outObj)
+			}
+			return expectedErr
+		})
+
+	return call
+}
+
+func expectGetSecret(mock *mock_client.MockClient, expectedOutput *corev1.Secret) *gomock.Call {
+	key := client.ObjectKey{
+		Namespace: expectedOutput.Namespace,
+		Name:      expectedOutput.Name,
+	}
+
+	call := mock.EXPECT().Get(gomock.Not(gomock.Nil()), key, gomock.AssignableToTypeOf(expectedOutput)).
+		DoAndReturn(func(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...interface{}) error {
+			expectedOutput.DeepCopyInto(obj.(*corev1.Secret))
+			return nil
+		})
+
+	return call
+}
+
+func expectListIngresses(mock *mock_client.MockClient, namespace, ownerName string, expectedItems []*networkingv1.Ingress, expectedErr error) *gomock.Call {
+	var list *networkingv1.IngressList
+	call := mock.EXPECT().List(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(list), client.InNamespace(namespace), client.MatchingFields{ingressOwnerKey: ownerName}).
+		DoAndReturn(func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			outObj := list.(*networkingv1.IngressList)
+			for _, item := range expectedItems {
+				outObj.Items = append(outObj.Items, *item)
+			}
+
+			return expectedErr
+		})
+
+	return call
+}
+
+func expectListIngressesByHost(mock *mock_client.MockClient, host string, expectedItems []*networkingv1.Ingress) *gomock.Call {
+	var list *networkingv1.IngressList
+	call := mock.EXPECT().List(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(list), client.MatchingFields{ingressHostKey: host}).
+		DoAndReturn(func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			outObj := list.(*networkingv1.IngressList)
+			for _, item := range expectedItems {
+				outObj.Items = append(outObj.Items, *item)
+			}
+
+			return nil
+		})
+
+	return call
+}
+
+func expectUpdateStatus(mock *mock_client.MockStatusWriter, expectedErr error) (*gomock.Call, *networkingv1alpha1.RandomIngressStatus) {
+	var r *networkingv1alpha1.RandomIngress
+
+	result := networkingv1alpha1.RandomIngressStatus{}
+	call := mock.EXPECT().Update(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(r))).
+		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+			ingress := obj.(*networkingv1alpha1.RandomIngress)
+			ingress.Status.DeepCopyInto(&result)
+
+			return expectedErr
+		})
+
+	return call, &result
+}
+
+func expectCreateIngress(mock *mock_client.MockClient, expectedErr error) (*gomock.Call, *networkingv1.Ingress) {
+	result := &networkingv1.Ingress{}
+
+	call := mock.EXPECT().Create(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(result))).
+		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+			ingress := obj.(*networkingv1.Ingress)
+			ingress.DeepCopyInto(result)
+
+			return expectedErr
+		})
+
+	return call, result
+}
+
+func expectListUnstructured(mock *mock_client.MockClient, gvk schema.GroupVersionKind, namespace, ownerName string, expectedItems []*unstructured.Unstructured) *gomock.Call {
+	var list *unstructured.UnstructuredList
+	call := mock.EXPECT().List(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(list), client.InNamespace(namespace), client.MatchingFields{ingressOwnerKey: ownerName}).
+		DoAndReturn(func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			outObj := list.(*unstructured.UnstructuredList)
+			if outObj.GroupVersionKind() != gvk.GroupVersion().WithKind(gvk.Kind+"List") {
+				return fmt.Errorf("unexpected list kind %s", outObj.GroupVersionKind())
+			}
+
+			for _, item := range expectedItems {
+				outObj.Items = append(outObj.Items, *item)
+			}
+
+			return nil
+		})
+
+	return call
+}
+
+func expectGetUnstructured(mock *mock_client.MockClient, expectedOutput *unstructured.Unstructured) *gomock.Call {
+	key := client.ObjectKey{
+		Namespace: expectedOutput.GetNamespace(),
+		Name:      expectedOutput.GetName(),
+	}
+
+	call := mock.EXPECT().Get(gomock.Not(gomock.Nil()), key, gomock.AssignableToTypeOf(expectedOutput)).
+		DoAndReturn(func(ctx context.Context, key client.ObjectKey, obj client.Object, _ ...interface{}) error {
+			outObj := obj.(*unstructured.Unstructured)
+			if outObj.GroupVersionKind() != expectedOutput.GroupVersionKind() {
+				return fmt.Errorf("unexpected kind %s", outObj.GroupVersionKind())
+			}
+
+			expectedOutput.DeepCopyInto(outObj)
+			return nil
+		})
+
+	return call
+}
+
+func expectUpdateUnstructured(mock *mock_client.MockClient, expectedErr error) (*gomock.Call, *unstructured.Unstructured) {
+	result := &unstructured.Unstructured{}
+
+	call := mock.EXPECT().Update(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(result))).
+		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+			obj.(*unstructured.Unstructured).DeepCopyInto(result)
+
+			return expectedErr
+		})
+
+	return call, result
+}
+
+func expectUpdateRandomIngress(mock *mock_client.MockClient) (*gomock.Call, *networkingv1alpha1.RandomIngress) {
+	result := &networkingv1alpha1.RandomIngress{}
+
+	call := mock.EXPECT().Update(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(result))).
+		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+			obj.(*networkingv1alpha1.RandomIngress).DeepCopyInto(result)
+
+			return nil
+		})
+
+	return call, result
+}
+
+func expectCreateUnstructured(mock *mock_client.MockClient, expectedErr error) (*gomock.Call, *unstructured.Unstructured) {
+	result := &unstructured.Unstructured{}
+
+	call := mock.EXPECT().Create(gomock.Not(gomock.Nil()), gomock.All(gomock.Not(gomock.Nil()), gomock.AssignableToTypeOf(result))).
+		DoAndReturn(func(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+			obj.(*unstructured.Unstructured).DeepCopyInto(result)
+
+			return expectedErr
+		})
+
+	return call, result
+}
+
+func expectDeleteIngress(mock *mock_client.MockClient, expectedIn *networkingv1.Ingress, expectedErr error) *gomock.Call {
+	call := mock.EXPECT().Delete(gomock.Not(gomock.Nil()), gomock.Eq(expectedIn)).
+		Return(expectedErr)
+
+	return call
+}
+
+// setExpectedCondition replaces the condition of the same type in the expected status.
+func setExpectedCondition(status *networkingv1alpha1.RandomIngressStatus, condition networkingv1alpha1.RandomIngressCondition) {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condition.Type {
+			status.Conditions[i] = condition
+			return
+		}
+	}
+
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// setExpectedActiveIngresses sets the expected active Ingresses, newest first, along with the current hosts.
+func setExpectedActiveIngresses(status *networkingv1alpha1.RandomIngressStatus, activeIngresses ...networkingv1alpha1.ActiveIngress) {
+	status.ActiveIngresses = activeIngresses
+	status.CurrentHosts = activeIngresses[0].Hosts
+}
+
+// newExpectedActiveIngress describes the given Ingress as it should be reported in the status.
+func newExpectedActiveIngress(ingress *networkingv1.Ingress, createdAt, expiresAt time.Time, phase networkingv1alpha1.ActiveIngressPhase) networkingv1alpha1.ActiveIngress {
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+
+	return networkingv1alpha1.ActiveIngress{
+		Name:      ingress.Name,
+		Hosts:     hosts,
+		CreatedAt: metav1.NewTime(createdAt),
+		ExpiresAt: metav1.NewTime(expiresAt),
+		Phase:     phase,
+	}
+}
+
+// newExpectedIngressDeletion describes the deletion of the given Ingress as it should be reported in the status.
+func newExpectedIngressDeletion(ingress *networkingv1.Ingress, expiresAt, deletedAt time.Time) *networkingv1alpha1.IngressDeletion {
+	return &networkingv1alpha1.IngressDeletion{
+		Name:      ingress.Name,
+		ExpiresAt: metav1.NewTime(expiresAt),
+		DeletedAt: metav1.NewTime(deletedAt),
+		Delay:     metav1.Duration{Duration: deletedAt.Sub(expiresAt)},
+	}
+}
+
+func assertStatusEquivalent(t *testing.T, expected, actual *networkingv1alpha1.RandomIngressStatus) {
+	if expected == nil || actual == nil {
+		if !assert.Equal(t, expected, actual) {
+			return
+		}
+	}
+
+	conditionsSorter := func(conds []networkingv1alpha1.RandomIngressCondition) func(i, j int) bool {
+		return func(i, j int) bool {
+			return conds[i].Type < conds[j].Type
+		}
+	}
+
+	sort.SliceStable(expected.Conditions, conditionsSorter(expected.Conditions))
+	sort.SliceStable(actual.Conditions, conditionsSorter(actual.Conditions))
+
+	assert.Equal(t, expected, actual)
+}
+
+func assertIngressMatchesTemplate(t *testing.T, template *networkingv1alpha1.RandomIngress, actual *networkingv1.Ingress, randomToken string) {
+	assert.NotNil(t, template)
+	assert.NotNil(t, actual)
+
+	assert.Equal(t, template.Namespace, actual.Namespace)
+	for key, value := range template.Spec.IngressTemplate.Metadata.Annotations {
+		assert.Equal(t, value, actual.Annotations[key])
+	}
+	assert.Contains(t, actual.Annotations, issuedAtAnnotation)
+	assert.Contains(t, actual.Annotations, expiresAtAnnotation)
 	assert.Equal(t, template.Spec.IngressTemplate.Metadata.Labels, actual.Labels)
 
 	// Expected format: randomingressname-123abcd-123abcd
@@ -501,7 +4318,7 @@ func assertIngressMatchesTemplate(t *testing.T, template *networkingv1alpha1.Ran
 	}
 
 	for i := range expectedRules {
-		expectedHost := strings.ReplaceAll(expectedRules[i].Host, randomPlaceholder, string(randomId))
+		expectedHost := strings.ReplaceAll(expectedRules[i].Host, randomPlaceholder, randomToken)
 		assert.Equal(t, expectedHost, actual.Spec.Rules[i].Host)
 
 		assert.Equal(t, expectedRules[i].IngressRuleValue, actual.Spec.Rules[i].IngressRuleValue)
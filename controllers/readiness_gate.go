@@ -0,0 +1,122 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+const (
+	newIngressNotReadyReason    = "NewIngressNotReady"
+	noIngressHeldReason         = "NoIngressHeld"
+	readinessGateDisabledReason = "ReadinessGateDisabled"
+)
+
+// readinessGate keeps expired Ingresses alive until their replacement is served, for at most maxExtension.
+type readinessGate struct {
+	matchAddresses bool
+	maxExtension   time.Duration
+}
+
+// holdExpiredIngresses splits the expired Ingresses between the ones to delete now, and the ones to keep alive
+// until the replacement, the newest of the alive Ingresses, is served.
+// Only Ingresses expired on time are held: outdated ones and immediate rotation requests are never delayed.
+func (r *RandomIngressReconciler) holdExpiredIngresses(expired, alive []client.Object, specHashes []string, settings rotationSettings) (deleted, held []client.Object) {
+	if settings.readinessGate == nil {
+		return expired, nil
+	}
+
+	replacement := newestIngress(alive)
+
+	for _, ingress := range expired {
+		switch {
+		case !ingressMatchesSpec(ingress, specHashes),
+			immediateRotationRequested(ingress, settings),
+			!r.Clock.Now().Before(ingressExpiresAt(ingress, settings).Add(settings.readinessGate.maxExtension)),
+			replacement != nil && ingressServedInstead(replacement, ingress, settings.readinessGate):
+			deleted = append(deleted, ingress)
+		default:
+			held = append(held, ingress)
+		}
+	}
+
+	return deleted, held
+}
+
+// heldIngressesProgressingCondition reports on the Progressing condition expired Ingresses held until their replacement is served.
+func (r *RandomIngressReconciler) heldIngressesProgressingCondition(replacement client.Object, held []client.Object) networkingv1alpha1.RandomIngressCondition {
+	return r.newCondition(networkingv1alpha1.RandomIngressProgressing, corev1.ConditionTrue, newIngressNotReadyReason,
+		heldIngressesMessage(replacement, held))
+}
+
+// newIngressNotReadyCondition reports whether expired Ingresses are held until their replacement is served.
+func (r *RandomIngressReconciler) newIngressNotReadyCondition(settings rotationSettings, replacement client.Object, held []client.Object) networkingv1alpha1.RandomIngressCondition {
+	switch {
+	case settings.readinessGate == nil:
+		return r.newCondition(networkingv1alpha1.RandomIngressNewIngressNotReady, corev1.ConditionFalse, readinessGateDisabledReason,
+			"no readiness gate is configured")
+	case len(held) == 0:
+		return r.newCondition(networkingv1alpha1.RandomIngressNewIngressNotReady, corev1.ConditionFalse, noIngressHeldReason,
+			"no expired Ingress is waiting for its replacement")
+	default:
+		return r.newCondition(networkingv1alpha1.RandomIngressNewIngressNotReady, corev1.ConditionTrue, newIngressNotReadyReason,
+			heldIngressesMessage(replacement, held))
+	}
+}
+
+// heldIngressesMessage describes expired Ingresses held until the given replacement, if any, is served.
+func heldIngressesMessage(replacement client.Object, held []client.Object) string {
+	if replacement == nil {
+		return fmt.Sprintf("no new Ingress is served yet, keeping %d expired Ingresses", len(held))
+	}
+
+	return fmt.Sprintf("Ingress %s is not served yet, keeping %d expired Ingresses", replacement.GetName(), len(held))
+}
+
+// ingressServedInstead returns true if the replacement is served, and with matchAddresses, by every load balancer
+// or gateway serving the object it replaces: Ingresses report load balancer addresses, HTTPRoutes the gateways accepting them.
+func ingressServedInstead(replacement, replaced client.Object, gate *readinessGate) bool {
+	servers := objectTarget(replacement).servedBy(replacement)
+	if len(servers) == 0 {
+		return false
+	}
+
+	if gate.matchAddresses {
+		for server := range objectTarget(replaced).servedBy(replaced) {
+			if !servers[server] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// immediateRotationRequested returns true if the Ingress must be deleted right away for a rotation request.
+func immediateRotationRequested(ingress client.Object, settings rotationSettings) bool {
+	request := settings.rotationRequest
+
+	return request != nil && request.Strategy == networkingv1alpha1.RotationStrategyImmediate &&
+		ingress.GetAnnotations()[rotationTokenAnnotation] != request.Token
+}
+
+// newestIngress returns the most recently issued of the given Ingresses, or nil if there is none.
+func newestIngress(ingresses []client.Object) client.Object {
+	var newest client.Object
+	for _, ingress := range ingresses {
+		if newest == nil || ingressIssuedAt(ingress).After(ingressIssuedAt(newest)) {
+			newest = ingress
+		}
+	}
+
+	return newest
+}
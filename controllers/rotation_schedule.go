@@ -0,0 +1,75 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+// scheduleIntervalsChecked is the number of consecutive ticks checked when validating
+// the interval between rotations of a schedule.
+const scheduleIntervalsChecked = 10
+
+// parseRotationSchedule parses the cron expression of the schedule, interpreted in its time zone.
+// Errors are reported against the given path of the schedule.
+func parseRotationSchedule(rotationSchedule *networkingv1alpha1.RotationSchedule, schedulePath *field.Path) (*cron.SpecSchedule, *field.Error) {
+	cronPath := schedulePath.Child("cron")
+
+	if strings.Contains(rotationSchedule.Cron, "TZ=") {
+		return nil, field.Invalid(cronPath, rotationSchedule.Cron, "time zone must be set through timeZone")
+	}
+
+	location := time.UTC
+	if rotationSchedule.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(rotationSchedule.TimeZone)
+		if err != nil {
+			return nil, field.Invalid(schedulePath.Child("timeZone"), rotationSchedule.TimeZone, "unknown time zone")
+		}
+	}
+
+	schedule, err := cron.ParseStandard(rotationSchedule.Cron)
+	if err != nil {
+		return nil, field.Invalid(cronPath, rotationSchedule.Cron, err.Error())
+	}
+
+	specSchedule, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return nil, field.Invalid(cronPath, rotationSchedule.Cron, "must be aligned on wall-clock times, @every is not supported")
+	}
+
+	specSchedule.Location = location
+
+	return specSchedule, nil
+}
+
+// shortestScheduleInterval returns the shortest interval between the next ticks of the schedule after now,
+// or zero if the schedule never fires.
+func shortestScheduleInterval(schedule cron.Schedule, now time.Time) time.Duration {
+	var shortest time.Duration
+
+	previous := schedule.Next(now)
+	for i := 0; i < scheduleIntervalsChecked && !previous.IsZero(); i++ {
+		next := schedule.Next(previous)
+		if next.IsZero() {
+			break
+		}
+
+		if interval := next.Sub(previous); shortest == 0 || interval < shortest {
+			shortest = interval
+		}
+
+		previous = next
+	}
+
+	return shortest
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+// target is a kind of object generated from RandomIngresses: Ingresses, or the routes of another API.
+// The rotation only relies on the metadata of the generated objects, the rest is up to the target.
+type target interface {
+	// groupVersionKind identifies the generated objects.
+	groupVersionKind() schema.GroupVersionKind
+
+	// newObject returns an empty object of the kind, to watch and index the generated objects.
+	newObject() client.Object
+
+	// list returns the objects of the kind matching the options.
+	list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error)
+
+	// templateMetadata returns the metadata of the template of the spec.
+	templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata
+
+	// templateHosts returns the hosts of the template, before substitution, in the order hosts returns them once generated.
+	templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string
+
+	// templateValues returns the values of the template, apart from its metadata, in which placeholders are substituted.
+	templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string
+
+	// render returns the object generated from the template of the spec, with the given metadata,
+	// once its placeholders are substituted with the tokens.
+	render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error)
+
+	// validate checks the template of the spec.
+	validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) field.ErrorList
+
+	// hosts returns the hosts served by a generated object.
+	hosts(obj client.Object) []string
+
+	// servedBy returns the load balancers or routers serving a generated object, none until it is served.
+	servedBy(obj client.Object) map[string]bool
+}
+
+// knownTargets lists every target the operator can generate objects for, whether enabled or not.
+var knownTargets = []target{
+	ingressTarget{},
+	httpRouteTarget{},
+	openShiftRouteTarget{},
+	virtualServiceTarget{},
+	traefikIngressRouteTarget{},
+}
+
+// targets returns the targets enabled on the operator, Ingresses first.
+func (r *RandomIngressReconciler) targets() []target {
+	targets := []target{ingressTarget{}}
+	if r.EnableHTTPRoutes {
+		targets = append(targets, httpRouteTarget{})
+	}
+	if r.EnableOpenShiftRoutes {
+		targets = append(targets, openShiftRouteTarget{})
+	}
+	if r.EnableVirtualServices {
+		targets = append(targets, virtualServiceTarget{})
+	}
+	if r.EnableTraefikIngressRoutes {
+		targets = append(targets, traefikIngressRouteTarget{})
+	}
+
+	return targets
+}
+
+// targetEnabled returns true if objects of the kind of the target can be generated.
+func (r *RandomIngressReconciler) targetEnabled(t target) bool {
+	for _, enabled := range r.targets() {
+		if enabled.groupVersionKind() == t.groupVersionKind() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// specTarget returns the target of the objects generated from the spec: Ingresses,
+// unless the template of another kind is set.
+func specTarget(spec *networkingv1alpha1.RandomIngressSpec) target {
+	switch {
+	case spec.HTTPRouteTemplate != nil:
+		return httpRouteTarget{}
+	case spec.OpenShiftRouteTemplate != nil:
+		return openShiftRouteTarget{}
+	case spec.VirtualServiceTemplate != nil:
+		return virtualServiceTarget{}
+	case spec.TraefikIngressRouteTemplate != nil:
+		return traefikIngressRouteTarget{}
+	default:
+		return ingressTarget{}
+	}
+}
+
+// objectTarget returns the target of a generated object.
+func objectTarget(obj client.Object) target {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		for _, t := range knownTargets {
+			if t.groupVersionKind() == u.GroupVersionKind() {
+				return t
+			}
+		}
+	}
+
+	return ingressTarget{}
+}
+
+// listOwnedObjects returns the objects of every enabled kind controlled by the named RandomIngress.
+// Objects of another kind than the one of the spec are outdated, and replaced like Ingresses of a previous template.
+func (r *RandomIngressReconciler) listOwnedObjects(ctx context.Context, namespace, name string) ([]client.Object, error) {
+	var owned []client.Object
+	for _, t := range r.targets() {
+		objects, err := t.list(ctx, r.Client, client.InNamespace(namespace), client.MatchingFields{ingressOwnerKey: name})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list owned %ss: %w", t.groupVersionKind().Kind, err)
+		}
+
+		owned = append(owned, objects...)
+	}
+
+	return owned, nil
+}
+
+// validateTemplate checks the template of the spec, and that the kind of the generated objects is enabled.
+func (r *RandomIngressReconciler) validateTemplate(spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	t := specTarget(spec)
+
+	if _, ok := t.(ingressTarget); !ok && !equalIngressTemplates(spec.IngressTemplate, networkingv1alpha1.IngressTemplateSpec{}) {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "ingressTemplate"),
+			fmt.Sprintf("must be empty when %ss are generated instead", t.groupVersionKind().Kind)))
+	}
+
+	if templates := routeTemplates(spec); len(templates) > 1 {
+		for _, template := range templates[1:] {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", template), fmt.Sprintf("cannot be set along with %s", templates[0])))
+		}
+	}
+
+	if !r.targetEnabled(t) {
+		errs = append(errs, field.Forbidden(templatePath(t), fmt.Sprintf("%ss are not enabled on the operator", t.groupVersionKind().Kind)))
+	}
+
+	return append(errs, t.validate(r, spec)...)
+}
+
+// routeTemplates returns the names of the templates of routes set in the spec, at most one of which is allowed.
+func routeTemplates(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	var templates []string
+	if spec.HTTPRouteTemplate != nil {
+		templates = append(templates, "httpRouteTemplate")
+	}
+	if spec.OpenShiftRouteTemplate != nil {
+		templates = append(templates, "openShiftRouteTemplate")
+	}
+	if spec.VirtualServiceTemplate != nil {
+		templates = append(templates, "virtualServiceTemplate")
+	}
+	if spec.TraefikIngressRouteTemplate != nil {
+		templates = append(templates, "traefikIngressRouteTemplate")
+	}
+
+	return templates
+}
+
+// templatePath returns the path of the template of the target in the spec.
+func templatePath(t target) *field.Path {
+	switch t.(type) {
+	case httpRouteTarget:
+		return field.NewPath("spec", "httpRouteTemplate")
+	case openShiftRouteTarget:
+		return field.NewPath("spec", "openShiftRouteTemplate")
+	case virtualServiceTarget:
+		return field.NewPath("spec", "virtualServiceTemplate")
+	case traefikIngressRouteTarget:
+		return field.NewPath("spec", "traefikIngressRouteTemplate")
+	default:
+		return field.NewPath("spec", "ingressTemplate")
+	}
+}
+
+// equalIngressTemplates compares Ingress templates through their JSON form, where unset and empty fields are the same.
+func equalIngressTemplates(a, b networkingv1alpha1.IngressTemplateSpec) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// unstructuredTarget generates objects of a kind whose API the operator does not depend on, as unstructured objects.
+type unstructuredTarget struct {
+	gvk schema.GroupVersionKind
+}
+
+func (t unstructuredTarget) groupVersionKind() schema.GroupVersionKind {
+	return t.gvk
+}
+
+func (t unstructuredTarget) newObject() client.Object {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(t.gvk)
+
+	return obj
+}
+
+func (t unstructuredTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(t.gvk.GroupVersion().WithKind(t.gvk.Kind + "List"))
+
+	if err := c.List(ctx, list, opts...); err != nil {
+		return nil, err
+	}
+
+	objects := make([]client.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+
+	return objects, nil
+}
+
+// newUnstructured returns an object of the kind of the target, with the given metadata and spec.
+func (t unstructuredTarget) newUnstructured(meta metav1.ObjectMeta, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetGroupVersionKind(t.gvk)
+	obj.SetName(meta.Name)
+	obj.SetNamespace(meta.Namespace)
+	obj.SetLabels(meta.Labels)
+	obj.SetAnnotations(meta.Annotations)
+
+	return obj
+}
+
+// decodeTemplateSpec returns the spec of an object template as unstructured content.
+func decodeTemplateSpec(template *networkingv1alpha1.ObjectTemplateSpec) (map[string]interface{}, error) {
+	spec := map[string]interface{}{}
+	if template == nil || len(template.Spec.Raw) == 0 {
+		return spec, nil
+	}
+
+	if err := json.Unmarshal(template.Spec.Raw, &spec); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// ingressTarget generates networking.k8s.io/v1 Ingresses from spec.ingressTemplate.
+type ingressTarget struct{}
+
+func (ingressTarget) groupVersionKind() schema.GroupVersionKind {
+	return networkingv1.SchemeGroupVersion.WithKind("Ingress")
+}
+
+func (ingressTarget) newObject() client.Object {
+	return &networkingv1.Ingress{}
+}
+
+func (ingressTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	var list networkingv1.IngressList
+	if err := c.List(ctx, &list, opts...); err != nil {
+		return nil, err
+	}
+
+	objects := make([]client.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objects = append(objects, &list.Items[i])
+	}
+
+	return objects, nil
+}
+
+func (ingressTarget) templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata {
+	return spec.IngressTemplate.Metadata
+}
+
+func (ingressTarget) templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	var hosts []string
+	for _, rule := range spec.IngressTemplate.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+
+	return hosts
+}
+
+func (ingressTarget) templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	var values []string
+	for _, rule := range spec.IngressTemplate.Spec.Rules {
+		values = append(values, rule.Host)
+
+		if rule.HTTP != nil {
+			for _, path := range rule.HTTP.Paths {
+				values = append(values, path.Path)
+			}
+		}
+	}
+
+	for _, tls := range spec.IngressTemplate.Spec.TLS {
+		values = append(values, tls.SecretName)
+		values = append(values, tls.Hosts...)
+	}
+
+	return values
+}
+
+func (ingressTarget) render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error) {
+	ingressSpec := spec.IngressTemplate.Spec.DeepCopy()
+
+	for i := range ingressSpec.Rules {
+		rule := &ingressSpec.Rules[i]
+		rule.Host = replacePlaceholders(rule.Host, tokens)
+
+		if rule.HTTP != nil {
+			for j := range rule.HTTP.Paths {
+				rule.HTTP.Paths[j].Path = replacePlaceholders(rule.HTTP.Paths[j].Path, tokens)
+			}
+		}
+	}
+
+	for i := range ingressSpec.TLS {
+		tls := &ingressSpec.TLS[i]
+		tls.SecretName = replacePlaceholders(tls.SecretName, tokens)
+
+		for j := range tls.Hosts {
+			tls.Hosts[j] = replacePlaceholders(tls.Hosts[j], tokens)
+		}
+	}
+
+	return &networkingv1.Ingress{ObjectMeta: meta, Spec: *ingressSpec}, nil
+}
+
+func (ingressTarget) validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	rulesPath := field.NewPath("spec", "ingressTemplate", "spec", "rules")
+
+	for i, rule := range spec.IngressTemplate.Spec.Rules {
+		errs = append(errs, validateRulePlaceholders(rule, rulesPath.Index(i))...)
+	}
+
+	// Generated hosts and labels are checked with the longest tokens, as long as their settings are valid:
+	// invalid token settings are reported on their own.
+	if r.placeholderTokensValid(spec) {
+		errs = append(errs, validateHosts(spec, rulesPath, errs)...)
+		errs = append(errs, validateLabels(spec, field.NewPath("spec", "ingressTemplate", "metadata", "labels"))...)
+	}
+
+	return append(errs, validateTLS(&spec.IngressTemplate.Spec, field.NewPath("spec", "ingressTemplate", "spec", "tls"))...)
+}
+
+func (ingressTarget) hosts(obj client.Object) []string {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return nil
+	}
+
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+
+	return hosts
+}
+
+// servedBy returns the load balancer addresses of the Ingress.
+func (ingressTarget) servedBy(obj client.Object) map[string]bool {
+	addresses := map[string]bool{}
+
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return addresses
+	}
+
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addresses[lb.IP] = true
+		}
+		if lb.Hostname != "" {
+			addresses[lb.Hostname] = true
+		}
+	}
+
+	return addresses
+}
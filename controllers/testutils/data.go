@@ -101,9 +101,66 @@ func NewValidRandomIngStatus(lastTransition, nextRenewal time.Time) *networkingv
 				LastHeartbeatTime:  metav1.NewTime(lastTransition),
 				LastTransitionTime: metav1.NewTime(lastTransition),
 			},
+			{
+				Type:               networkingv1alpha1.RandomIngressProgressing,
+				Status:             corev1.ConditionFalse,
+				Reason:             "SingleIngressLive",
+				Message:            "a single Ingress is live",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressSuspended,
+				Status:             corev1.ConditionFalse,
+				Reason:             "RotationActive",
+				Message:            "rotation is active",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressRotationDeferred,
+				Status:             corev1.ConditionFalse,
+				Reason:             "NotDeferred",
+				Message:            "rotation is not deferred",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressReplacementFailed,
+				Status:             corev1.ConditionFalse,
+				Reason:             "NoFailure",
+				Message:            "no replacement failed",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressPerHostCertificateRequired,
+				Status:             corev1.ConditionFalse,
+				Reason:             "NoRandomTLSHosts",
+				Message:            "no TLS host needs a per-host certificate",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressHostConflict,
+				Status:             corev1.ConditionFalse,
+				Reason:             "DetectionDisabled",
+				Message:            "host conflict detection is disabled",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
+			{
+				Type:               networkingv1alpha1.RandomIngressNewIngressNotReady,
+				Status:             corev1.ConditionFalse,
+				Reason:             "ReadinessGateDisabled",
+				Message:            "no readiness gate is configured",
+				LastHeartbeatTime:  metav1.NewTime(lastTransition),
+				LastTransitionTime: metav1.NewTime(lastTransition),
+			},
 		},
 
-		NextRenewalTime: &nextRenewalTime,
+		TokenEntropyBits: 122,
+		NextRenewalTime:  &nextRenewalTime,
 	}
 
 	return s
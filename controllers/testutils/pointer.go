@@ -12,3 +12,13 @@ package testutils
 func BoolPtr(b bool) *bool {
 	return &b
 }
+
+// Int32Ptr returns a pointer to an int32
+func Int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// StringPtr returns a pointer to a string
+func StringPtr(s string) *string {
+	return &s
+}
@@ -9,25 +9,26 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
-	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/token"
 )
 
-type FakeUUIDSource struct {
+type FakeTokenSource struct {
 	t     *testing.T
-	Items []types.UID
+	Items []string
 }
 
-func (s *FakeUUIDSource) NewUUID() types.UID {
+func (s *FakeTokenSource) NewToken(token.Settings) (string, error) {
 	require.Greater(s.t, len(s.Items), 0)
 
 	res, tail := s.Items[0], s.Items[1:]
 	s.Items = tail
 
-	return res
+	return res, nil
 }
 
-func NewFakeUUIDSource(t *testing.T, items []types.UID) *FakeUUIDSource {
-	return &FakeUUIDSource{
+func NewFakeTokenSource(t *testing.T, items []string) *FakeTokenSource {
+	return &FakeTokenSource{
 		t:     t,
 		Items: items,
 	}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+const (
+	randomTLSHostsReason   = "RandomTLSHosts"
+	noRandomTLSHostsReason = "NoRandomTLSHosts"
+)
+
+// validateTLS checks that every TLS host of the template is consistent with a rule host,
+// and that the TLS secret names remain valid once their placeholders are substituted.
+func validateTLS(spec *networkingv1.IngressSpec, tlsPath *field.Path) (errs field.ErrorList) {
+	for i, tls := range spec.TLS {
+		for j, host := range tls.Hosts {
+			if !tlsHostMatchesRules(host, spec.Rules) {
+				errs = append(errs, field.Invalid(tlsPath.Index(i).Child("hosts").Index(j), host, "does not match any rule host"))
+			}
+		}
+
+		if placeholderPattern.MatchString(tls.SecretName) {
+			// Tokens are made of lowercase letters, digits and dashes, so any token gives the same result.
+			substituted := placeholderPattern.ReplaceAllString(tls.SecretName, "token")
+			for _, msg := range validation.IsDNS1123Subdomain(substituted) {
+				errs = append(errs, field.Invalid(tlsPath.Index(i).Child("secretName"), tls.SecretName, msg))
+			}
+		}
+	}
+
+	return errs
+}
+
+// tlsHostMatchesRules returns true if the TLS host, before substitution, is a rule host or a wildcard covering one.
+func tlsHostMatchesRules(tlsHost string, rules []networkingv1.IngressRule) bool {
+	hosts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		hosts = append(hosts, rule.Host)
+	}
+
+	return tlsHostMatchesHosts(tlsHost, hosts)
+}
+
+// tlsHostMatchesHosts returns true if the TLS host, before substitution, is one of the hosts or a wildcard covering one.
+// Placeholders are substituted the same way in TLS and other hosts, and tokens never contain dots.
+func tlsHostMatchesHosts(tlsHost string, hosts []string) bool {
+	for _, host := range hosts {
+		if host == tlsHost {
+			return true
+		}
+
+		if domain := strings.TrimPrefix(tlsHost, "*."); domain != tlsHost {
+			if label, hostDomain, found := strings.Cut(host, "."); found && label != "" && hostDomain == domain {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// perHostCertificateCondition reports whether the TLS section of the template lists random hosts, which need
+// a certificate for every generated Ingress. Hosts covered by a wildcard, like *.example.com, can share a certificate.
+func (r *RandomIngressReconciler) perHostCertificateCondition(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.RandomIngressCondition {
+	var randomHosts []string
+	for _, tls := range spec.IngressTemplate.Spec.TLS {
+		for _, host := range tls.Hosts {
+			if placeholderPattern.MatchString(host) {
+				randomHosts = append(randomHosts, host)
+			}
+		}
+	}
+
+	if len(randomHosts) == 0 {
+		return r.newCondition(networkingv1alpha1.RandomIngressPerHostCertificateRequired, corev1.ConditionFalse, noRandomTLSHostsReason,
+			"no TLS host needs a per-host certificate")
+	}
+
+	message := fmt.Sprintf("TLS hosts %s need a certificate issued for every generated Ingress", strings.Join(randomHosts, ", "))
+	return r.newCondition(networkingv1alpha1.RandomIngressPerHostCertificateRequired, corev1.ConditionTrue, randomTLSHostsReason, message)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/token"
+)
+
+// maxTokenGenerationAttempts is the number of times a token is generated
+// before giving up on finding one that the token history does not reject.
+const maxTokenGenerationAttempts = 3
+
+// tokenHistorySaltBytes is the size of the salt of new token histories.
+const tokenHistorySaltBytes = 16
+
+// hashToken returns the salted hash of a token, as recorded in the token history.
+// It is truncated to 128 bits, which is plenty to tell apart the tokens of a bounded history.
+func hashToken(salt []byte, value string) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(value))
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// tokenReused returns true if the token history holds the token, and it expired at the given time.
+// A token which is still live, e.g. derived again from the same secret within the same epoch,
+// does not expose any leaked host and may be issued again.
+func tokenReused(history *networkingv1alpha1.TokenHistory, value string, now time.Time) bool {
+	if history == nil {
+		return false
+	}
+
+	salt, err := hex.DecodeString(history.Salt)
+	if err != nil {
+		return false
+	}
+
+	hash := hashToken(salt, value)
+	for _, entry := range history.Entries {
+		if entry.Hash == hash && !entry.ExpiresAt.Time.After(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newToken generates a token from the source. When the token history is enabled, tokens which
+// were already issued and expired are rejected, and generated again.
+func (r *RandomIngressReconciler) newToken(source token.Source, settings token.Settings, history *networkingv1alpha1.TokenHistory, issuedAt time.Time) (string, error) {
+	for attempt := 0; attempt < maxTokenGenerationAttempts; attempt++ {
+		value, err := source.NewToken(settings)
+		if err != nil || r.TokenHistorySize <= 0 || !tokenReused(history, value, issuedAt) {
+			return value, err
+		}
+	}
+
+	return "", fmt.Errorf("failed to generate a token that was never issued in %d attempts", maxTokenGenerationAttempts)
+}
+
+// recordIngressTokens records the tokens issued for the given Ingresses, oldest first. It runs on every reconcile
+// rather than once the Ingresses are created, so that tokens whose record was lost along with a failed status
+// update are recorded again as long as their Ingress exists.
+func (r *RandomIngressReconciler) recordIngressTokens(status *networkingv1alpha1.RandomIngressStatus, ingresses []client.Object) error {
+	sorted := append([]client.Object(nil), ingresses...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ingressIssuedAt(sorted[i]).Before(ingressIssuedAt(sorted[j]))
+	})
+
+	for _, ingress := range sorted {
+		if err := r.recordIssuedTokens(status, ingress); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordIssuedTokens adds the tokens issued for the Ingress to the token history of the status, keeping
+// the TokenHistorySize most recent ones. Tokens carried over from a previous Ingress are already recorded,
+// as are tokens with an entry expiring no earlier, which makes recording the same Ingress again a no-op.
+// The history starts over with a new salt if it has none, or an invalid one.
+func (r *RandomIngressReconciler) recordIssuedTokens(status *networkingv1alpha1.RandomIngressStatus, ingress client.Object) error {
+	tokens, _ := ingressTokens(ingress)
+	issuedAt := ingressIssuedAt(ingress)
+	names := make([]string, 0, len(tokens))
+	for name, issued := range tokens {
+		if issued.IssuedAt.Time.Equal(issuedAt) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	history := status.TokenHistory.DeepCopy()
+
+	var salt []byte
+	if history != nil {
+		salt, _ = hex.DecodeString(history.Salt)
+	}
+	if len(salt) == 0 {
+		salt = make([]byte, tokenHistorySaltBytes)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate token history salt: %w", err)
+		}
+
+		history = &networkingv1alpha1.TokenHistory{Salt: hex.EncodeToString(salt)}
+	}
+
+	for _, name := range names {
+		issued := tokens[name]
+		hash := hashToken(salt, issued.Value)
+		if tokenRecorded(history, hash, issued.ExpiresAt.Time) {
+			continue
+		}
+
+		// A token issued again replaces its previous entry, along with its new expiration.
+		entries := history.Entries[:0]
+		for _, entry := range history.Entries {
+			if entry.Hash != hash {
+				entries = append(entries, entry)
+			}
+		}
+
+		history.Entries = append(entries, networkingv1alpha1.TokenHistoryEntry{Hash: hash, ExpiresAt: issued.ExpiresAt})
+	}
+
+	if len(history.Entries) > r.TokenHistorySize {
+		history.Entries = history.Entries[len(history.Entries)-r.TokenHistorySize:]
+	}
+
+	status.TokenHistory = history
+	return nil
+}
+
+// tokenRecorded returns true if the token history holds an entry for the hash expiring no earlier than the given time.
+func tokenRecorded(history *networkingv1alpha1.TokenHistory, hash string, expiresAt time.Time) bool {
+	for _, entry := range history.Entries {
+		if entry.Hash == hash && !entry.ExpiresAt.Time.Before(expiresAt) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+// traefikIngressRouteGVK identifies Traefik IngressRoutes, which are handled as unstructured objects.
+var traefikIngressRouteGVK = schema.GroupVersionKind{Group: "traefik.io", Version: "v1alpha1", Kind: "IngressRoute"}
+
+var (
+	// hostMatcherPattern matches the Host matchers of Traefik rules, like Host(`a.example.com`, `b.example.com`).
+	// HostSNI and HostRegexp matchers are left out.
+	hostMatcherPattern = regexp.MustCompile(`\bHost\(([^)]*)\)`)
+
+	// singleHostMatcherPattern matches a rule made of a single Host matcher.
+	singleHostMatcherPattern = regexp.MustCompile(`^Host\([^)]*\)$`)
+
+	// quotedPattern matches the quoted arguments of Traefik matchers.
+	quotedPattern = regexp.MustCompile("[`\"]([^`\"]*)[`\"]")
+)
+
+// traefikIngressRouteTarget generates Traefik IngressRoutes from spec.traefikIngressRouteTemplate.
+// Placeholders are substituted in the match rules of the routes and in the TLS domains.
+type traefikIngressRouteTarget struct{}
+
+func (traefikIngressRouteTarget) base() unstructuredTarget {
+	return unstructuredTarget{gvk: traefikIngressRouteGVK}
+}
+
+func (t traefikIngressRouteTarget) groupVersionKind() schema.GroupVersionKind {
+	return traefikIngressRouteGVK
+}
+
+func (t traefikIngressRouteTarget) newObject() client.Object {
+	return t.base().newObject()
+}
+
+func (t traefikIngressRouteTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	return t.base().list(ctx, c, opts...)
+}
+
+func (traefikIngressRouteTarget) templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata {
+	if spec.TraefikIngressRouteTemplate == nil {
+		return networkingv1alpha1.IngressTemplateMetadata{}
+	}
+
+	return spec.TraefikIngressRouteTemplate.Metadata
+}
+
+func (traefikIngressRouteTarget) templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	routeSpec, err := decodeTemplateSpec(spec.TraefikIngressRouteTemplate)
+	if err != nil {
+		return nil
+	}
+
+	return traefikHosts(routeSpec)
+}
+
+func (traefikIngressRouteTarget) templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	routeSpec, err := decodeTemplateSpec(spec.TraefikIngressRouteTemplate)
+	if err != nil {
+		return nil
+	}
+
+	var values []string
+	visitTraefikValues(routeSpec, func(value string) string {
+		values = append(values, value)
+		return value
+	})
+
+	return values
+}
+
+func (t traefikIngressRouteTarget) render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error) {
+	routeSpec, err := decodeTemplateSpec(spec.TraefikIngressRouteTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	visitTraefikValues(routeSpec, func(value string) string {
+		return replacePlaceholders(value, tokens)
+	})
+
+	return t.base().newUnstructured(meta, routeSpec), nil
+}
+
+// validate checks that every route only matches random hosts, and that the TLS domains cover them.
+// Traefik IngressRoutes report no status, so the readiness gate cannot tell when they are served.
+func (t traefikIngressRouteTarget) validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	specPath := field.NewPath("spec", "traefikIngressRouteTemplate", "spec")
+	routesPath := specPath.Child("routes")
+
+	if spec.ReadinessGate != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "readinessGate"), "IngressRoutes report no status to wait for"))
+	}
+
+	routeSpec, err := decodeTemplateSpec(spec.TraefikIngressRouteTemplate)
+	if err != nil {
+		return append(errs, field.Invalid(specPath, string(spec.TraefikIngressRouteTemplate.Spec.Raw), err.Error()))
+	}
+
+	routes, found, err := unstructured.NestedSlice(routeSpec, "routes")
+	switch {
+	case err != nil:
+		return append(errs, field.Invalid(routesPath, routeSpec["routes"], "must be a list of routes"))
+	case !found || len(routes) == 0:
+		return append(errs, field.Required(routesPath, "at least one route is required"))
+	}
+
+	// Several routes usually share a host, with different paths: each host is validated once.
+	var hosts []string
+	var hostPaths []*field.Path
+	seen := map[string]bool{}
+	for i, route := range routes {
+		matchPath := routesPath.Index(i).Child("match")
+
+		fields, _ := route.(map[string]interface{})
+		match, _, err := unstructured.NestedString(fields, "match")
+		if err != nil {
+			errs = append(errs, field.Invalid(matchPath, fields["match"], "must be a rule"))
+			continue
+		}
+
+		matchErrs := validateMatch(match, matchPath)
+		errs = append(errs, matchErrs...)
+		if len(matchErrs) > 0 {
+			continue
+		}
+
+		for _, host := range matchedHosts(match) {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+				hostPaths = append(hostPaths, matchPath)
+			}
+		}
+	}
+
+	errs = append(errs, validateTraefikDomains(routeSpec, hosts, specPath.Child("tls", "domains"))...)
+
+	if r.placeholderTokensValid(spec) {
+		errs = append(errs, validateHostList(spec, hosts, func(i int) *field.Path { return hostPaths[i] }, errs)...)
+		errs = append(errs, validateLabels(spec, field.NewPath("spec", "traefikIngressRouteTemplate", "metadata", "labels"))...)
+	}
+
+	return errs
+}
+
+// validateMatch checks that a match rule only matches random hosts: it must be a conjunction of matchers
+// including a Host matcher, and every host of its Host matchers must carry a placeholder.
+func validateMatch(match string, matchPath *field.Path) (errs field.ErrorList) {
+	hosts := matchedHosts(match)
+	for _, host := range hosts {
+		if !placeholderPattern.MatchString(host) {
+			errs = append(errs, field.Invalid(matchPath, match, fmt.Sprintf("host %s: %s", host, randomPlaceholderMissingError)))
+		}
+	}
+
+	if len(hosts) == 0 || !restrictedToHosts(match) {
+		errs = append(errs, field.Invalid(matchPath, match, "must be restricted to random hosts by a Host matcher, "+
+			"combined with other matchers by && only"))
+	}
+
+	return errs
+}
+
+// restrictedToHosts returns true if the rule is a conjunction, at its top level, of matchers among which a Host matcher:
+// a disjunction could match other hosts.
+func restrictedToHosts(match string) bool {
+	var conjuncts []string
+	depth, start := 0, 0
+	var quote rune
+
+	for i, c := range match {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '`' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(match[i:], "||"):
+			return false
+		case depth == 0 && strings.HasPrefix(match[i:], "&&"):
+			conjuncts = append(conjuncts, match[start:i])
+			start = i + 2
+		}
+	}
+	conjuncts = append(conjuncts, match[start:])
+
+	for _, conjunct := range conjuncts {
+		if singleHostMatcherPattern.MatchString(strings.TrimSpace(conjunct)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateTraefikDomains checks that the main and alternative names of the TLS domains are hosts of the routes,
+// or wildcards covering them, as for the TLS hosts of Ingresses.
+func validateTraefikDomains(routeSpec map[string]interface{}, hosts []string, domainsPath *field.Path) (errs field.ErrorList) {
+	domains, _, _ := unstructured.NestedSlice(routeSpec, "tls", "domains")
+	for i, domain := range domains {
+		fields, _ := domain.(map[string]interface{})
+
+		if main, ok := fields["main"].(string); ok && !tlsHostMatchesHosts(main, hosts) {
+			errs = append(errs, field.Invalid(domainsPath.Index(i).Child("main"), main, "does not match any route host"))
+		}
+
+		sans, _ := fields["sans"].([]interface{})
+		for j, san := range sans {
+			if name, ok := san.(string); ok && !tlsHostMatchesHosts(name, hosts) {
+				errs = append(errs, field.Invalid(domainsPath.Index(i).Child("sans").Index(j), name, "does not match any route host"))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (traefikIngressRouteTarget) hosts(obj client.Object) []string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	routeSpec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	return traefikHosts(routeSpec)
+}
+
+// servedBy returns nothing: IngressRoutes report no status.
+func (traefikIngressRouteTarget) servedBy(client.Object) map[string]bool {
+	return map[string]bool{}
+}
+
+// traefikHosts returns the hosts of the Host matchers of the routes of an IngressRoute spec.
+func traefikHosts(routeSpec map[string]interface{}) []string {
+	routes, _, _ := unstructured.NestedSlice(routeSpec, "routes")
+
+	var hosts []string
+	for _, route := range routes {
+		fields, _ := route.(map[string]interface{})
+		match, _, _ := unstructured.NestedString(fields, "match")
+		hosts = append(hosts, matchedHosts(match)...)
+	}
+
+	return hosts
+}
+
+// matchedHosts returns the hosts of the Host matchers of a Traefik rule.
+func matchedHosts(match string) []string {
+	var hosts []string
+	for _, matcher := range hostMatcherPattern.FindAllStringSubmatch(match, -1) {
+		for _, quoted := range quotedPattern.FindAllStringSubmatch(matcher[1], -1) {
+			hosts = append(hosts, quoted[1])
+		}
+	}
+
+	return hosts
+}
+
+// visitTraefikValues replaces the values of an IngressRoute spec in which placeholders are substituted:
+// the match rules of the routes, and the main and alternative names of the TLS domains.
+func visitTraefikValues(routeSpec map[string]interface{}, visit func(string) string) {
+	routes, _, _ := unstructured.NestedSlice(routeSpec, "routes")
+	for _, route := range routes {
+		if fields, ok := route.(map[string]interface{}); ok {
+			if match, ok := fields["match"].(string); ok {
+				fields["match"] = visit(match)
+			}
+		}
+	}
+	if routes != nil {
+		_ = unstructured.SetNestedSlice(routeSpec, routes, "routes")
+	}
+
+	domains, _, _ := unstructured.NestedSlice(routeSpec, "tls", "domains")
+	for _, domain := range domains {
+		fields, ok := domain.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if main, ok := fields["main"].(string); ok {
+			fields["main"] = visit(main)
+		}
+
+		if sans, ok := fields["sans"].([]interface{}); ok {
+			for i, san := range sans {
+				if name, ok := san.(string); ok {
+					sans[i] = visit(name)
+				}
+			}
+		}
+	}
+	if domains != nil {
+		_ = unstructured.SetNestedSlice(routeSpec, domains, "tls", "domains")
+	}
+}
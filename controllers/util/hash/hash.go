@@ -7,6 +7,7 @@ package hash
 
 import (
 	"encoding/hex"
+	"fmt"
 	"hash"
 	"hash/fnv"
 
@@ -35,8 +36,40 @@ func DeepHashObject(hasher hash.Hash, objectToWrite interface{}) {
 	printer.Fprintf(hasher, "%#v", objectToWrite)
 }
 
+// RandomIngressSpec returns a short hash identifying the template of the given spec: the Ingress template,
+// or the template of the other kind of object generated instead.
+// Rotation settings are deliberately left out: changing them must not replace live Ingresses.
 func RandomIngressSpec(spec *networkingv1alpha1.RandomIngressSpec) string {
+	var template interface{} = spec.IngressTemplate
+	switch {
+	case spec.HTTPRouteTemplate != nil:
+		template = spec.HTTPRouteTemplate
+	case spec.OpenShiftRouteTemplate != nil:
+		template = spec.OpenShiftRouteTemplate
+	case spec.VirtualServiceTemplate != nil:
+		// The Gateway is synced with the live VirtualServices, changing it does not replace them.
+		template = spec.VirtualServiceTemplate.ObjectTemplateSpec
+	case spec.TraefikIngressRouteTemplate != nil:
+		template = spec.TraefikIngressRouteTemplate
+	}
+
+	specHasher := fnv.New32a()
+	DeepHashObject(specHasher, template)
+	return rand.SafeEncodeString(hex.EncodeToString(specHasher.Sum(nil)))
+}
+
+// LegacyRandomIngressSpec returns the hash which named the Ingresses generated from the given spec before it held
+// rotation settings: it covered the whole spec, which was then made of the Ingress template only.
+func LegacyRandomIngressSpec(spec *networkingv1alpha1.RandomIngressSpec) string {
+	printer := spew.ConfigState{
+		Indent:         " ",
+		SortKeys:       true,
+		DisableMethods: true,
+		SpewKeys:       true,
+	}
+
+	// Rebuild what DeepHashObject printed for the legacy spec, which wraps what it prints for the template.
 	specHasher := fnv.New32a()
-	DeepHashObject(specHasher, spec)
+	fmt.Fprintf(specHasher, "(*v1alpha1.RandomIngressSpec){IngressTemplate:%s}", printer.Sprintf("%#v", spec.IngressTemplate))
 	return rand.SafeEncodeString(hex.EncodeToString(specHasher.Sum(nil)))
 }
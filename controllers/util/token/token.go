@@ -0,0 +1,268 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"strings"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+const (
+	// DefaultEntropyBits is the entropy of tokens which do not set it explicitly.
+	DefaultEntropyBits = 128
+
+	// UUIDv4EntropyBits is the entropy of random UUIDs: 6 of their 128 bits are fixed.
+	UUIDv4EntropyBits = 122
+
+	// DefaultWordsEntropyBits is the entropy of words tokens which set neither the entropy nor the number of words.
+	// Words tokens cannot reach the entropy of the other formats within a DNS label: 64 bits take 5 words.
+	DefaultWordsEntropyBits = 64
+
+	// DefaultSeparator joins the words of words tokens which do not set a separator.
+	DefaultSeparator = "-"
+)
+
+var alphabets = map[networkingv1alpha1.TokenFormat]string{
+	networkingv1alpha1.TokenFormatHex:    "0123456789abcdef",
+	networkingv1alpha1.TokenFormatBase32: "abcdefghijklmnopqrstuvwxyz234567",
+	networkingv1alpha1.TokenFormatBase36: "0123456789abcdefghijklmnopqrstuvwxyz",
+}
+
+// wordList is the EFF large diceware list of 7776 English words, of 3 to 9 letters, chosen to be easy to remember
+// and to type: https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed wordlist.txt
+var wordList string
+
+var words = strings.Fields(wordList)
+
+// Settings are the effective settings of a token, once the defaults have been applied.
+type Settings struct {
+	Format networkingv1alpha1.TokenFormat
+
+	// EntropyBits is the requested entropy, for alphabet formats.
+	EntropyBits int
+
+	// WordCount and Separator apply to words tokens.
+	WordCount int
+	Separator string
+}
+
+// NewSettings applies the defaults to the given token spec.
+func NewSettings(spec *networkingv1alpha1.TokenSpec) Settings {
+	settings := Settings{
+		Format:      networkingv1alpha1.TokenFormatUUIDv4,
+		EntropyBits: DefaultEntropyBits,
+		Separator:   DefaultSeparator,
+	}
+
+	if spec != nil {
+		if spec.Format != "" {
+			settings.Format = spec.Format
+		}
+
+		if spec.EntropyBits != nil {
+			settings.EntropyBits = int(*spec.EntropyBits)
+		}
+
+		if spec.Separator != nil {
+			settings.Separator = *spec.Separator
+		}
+	}
+
+	switch settings.Format {
+	case networkingv1alpha1.TokenFormatUUIDv4:
+		settings.EntropyBits = UUIDv4EntropyBits
+	case networkingv1alpha1.TokenFormatWords:
+		if spec == nil || spec.EntropyBits == nil {
+			settings.EntropyBits = DefaultWordsEntropyBits
+		}
+
+		settings.WordCount = int(math.Ceil(float64(settings.EntropyBits) / bitsPerSymbol(len(words))))
+		if spec != nil && spec.WordCount != nil {
+			settings.WordCount = int(*spec.WordCount)
+		}
+	}
+
+	return settings
+}
+
+// Entropy returns the number of random bits of the tokens.
+func (s Settings) Entropy() float64 {
+	switch s.Format {
+	case networkingv1alpha1.TokenFormatUUIDv4:
+		return UUIDv4EntropyBits
+	case networkingv1alpha1.TokenFormatWords:
+		return float64(s.WordCount) * bitsPerSymbol(len(words))
+	default:
+		return float64(s.Length()) * bitsPerSymbol(len(alphabets[s.Format]))
+	}
+}
+
+// Length returns the maximum number of characters of the tokens.
+func (s Settings) Length() int {
+	switch s.Format {
+	case networkingv1alpha1.TokenFormatUUIDv4:
+		return 36
+	case networkingv1alpha1.TokenFormatWords:
+		if s.WordCount <= 0 {
+			return 0
+		}
+
+		longest := 0
+		for _, word := range words {
+			if len(word) > longest {
+				longest = len(word)
+			}
+		}
+
+		return s.WordCount*longest + (s.WordCount-1)*len(s.Separator)
+	default:
+		return int(math.Ceil(float64(s.EntropyBits) / bitsPerSymbol(len(alphabets[s.Format]))))
+	}
+}
+
+// Source represents a source of random tokens.
+// It can be used in tests to predetermine the returned tokens.
+type Source interface {
+	NewToken(settings Settings) (string, error)
+}
+
+// CryptoSource generates tokens from a cryptographically secure random number generator.
+type CryptoSource struct{}
+
+// NewToken returns a random token with the given settings.
+func (CryptoSource) NewToken(settings Settings) (string, error) {
+	return generate(settings, rand.Reader)
+}
+
+// DerivedSource derives tokens deterministically from a secret key and a message:
+// the same key and message always give the same token.
+type DerivedSource struct {
+	Key     []byte
+	Message []byte
+}
+
+// NewToken returns the token derived from the key and message, with the given settings.
+func (s DerivedSource) NewToken(settings Settings) (string, error) {
+	return generate(settings, &hmacStream{mac: hmac.New(sha256.New, s.Key), message: s.Message})
+}
+
+// generate draws a token with the given settings from the bytes of random.
+func generate(settings Settings, random io.Reader) (string, error) {
+	switch settings.Format {
+	case networkingv1alpha1.TokenFormatUUIDv4:
+		return newUUIDv4(random)
+	case networkingv1alpha1.TokenFormatWords:
+		return newWords(random, settings.WordCount, settings.Separator)
+	}
+
+	alphabet, ok := alphabets[settings.Format]
+	if !ok {
+		return "", fmt.Errorf("unsupported token format %q", settings.Format)
+	}
+
+	var token strings.Builder
+	for i := 0; i < settings.Length(); i++ {
+		index, err := randomIndex(random, len(alphabet))
+		if err != nil {
+			return "", err
+		}
+
+		token.WriteByte(alphabet[index])
+	}
+
+	return token.String(), nil
+}
+
+func newUUIDv4(random io.Reader) (string, error) {
+	var uuid [16]byte
+	if _, err := io.ReadFull(random, uuid[:]); err != nil {
+		return "", err
+	}
+
+	// Version 4, variant RFC 4122.
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+func newWords(random io.Reader, count int, separator string) (string, error) {
+	picked := make([]string, count)
+	for i := range picked {
+		index, err := randomIndex(random, len(words))
+		if err != nil {
+			return "", err
+		}
+
+		picked[i] = words[index]
+	}
+
+	return strings.Join(picked, separator), nil
+}
+
+// randomIndex returns a uniformly distributed number in [0, n), for n up to 65536.
+// Values which would bias the result are rejected, so that derived tokens do not depend on the Go version.
+func randomIndex(random io.Reader, n int) (int, error) {
+	limit := 1<<16 - 1<<16%n
+
+	var buf [2]byte
+	for {
+		if _, err := io.ReadFull(random, buf[:]); err != nil {
+			return 0, err
+		}
+
+		if value := int(binary.BigEndian.Uint16(buf[:])); value < limit {
+			return value % n, nil
+		}
+	}
+}
+
+// hmacStream is an endless stream of pseudo-random bytes: the concatenation of HMAC(key, message || counter)
+// for successive 32-bit big-endian counters.
+type hmacStream struct {
+	mac     hash.Hash
+	message []byte
+	counter uint32
+	buffer  []byte
+}
+
+func (s *hmacStream) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.buffer) == 0 {
+			var counter [4]byte
+			binary.BigEndian.PutUint32(counter[:], s.counter)
+			s.counter++
+
+			s.mac.Reset()
+			s.mac.Write(s.message)
+			s.mac.Write(counter[:])
+			s.buffer = s.mac.Sum(nil)
+		}
+
+		copied := copy(p[n:], s.buffer)
+		s.buffer = s.buffer[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
+
+func bitsPerSymbol(symbols int) float64 {
+	return math.Log2(float64(symbols))
+}
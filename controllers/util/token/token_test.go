@@ -0,0 +1,102 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+	"github.com/BackMarket-oss/random-ingress-operator/controllers/util/token"
+)
+
+func TestCryptoTokenSource(t *testing.T) {
+	testCases := []struct {
+		name            string
+		settings        token.Settings
+		pattern         string
+		expectedEntropy int
+	}{
+		{
+			name:            "uuidv4",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatUUIDv4},
+			pattern:         `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+			expectedEntropy: 122,
+		},
+		{
+			name:            "hex",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatHex, EntropyBits: 128},
+			pattern:         `^[0-9a-f]{32}$`,
+			expectedEntropy: 128,
+		},
+		{
+			name:            "base32",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatBase32, EntropyBits: 128},
+			pattern:         `^[a-z2-7]{26}$`,
+			expectedEntropy: 130,
+		},
+		{
+			name:            "base36",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatBase36, EntropyBits: 160},
+			pattern:         `^[0-9a-z]{31}$`,
+			expectedEntropy: 160,
+		},
+		{
+			name:            "words",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatWords, WordCount: 5, Separator: "-"},
+			pattern:         `^[a-z-]{3,9}(-[a-z-]{3,9}){4}$`,
+			expectedEntropy: 64,
+		},
+		{
+			name:            "words without separator",
+			settings:        token.Settings{Format: networkingv1alpha1.TokenFormatWords, WordCount: 3},
+			pattern:         `^[a-z-]{9,27}$`,
+			expectedEntropy: 38,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedEntropy, int(tc.settings.Entropy()))
+
+			first, err := token.CryptoSource{}.NewToken(tc.settings)
+			assert.NoError(t, err)
+			assert.Regexp(t, tc.pattern, first)
+			assert.LessOrEqual(t, len(first), tc.settings.Length())
+
+			second, err := token.CryptoSource{}.NewToken(tc.settings)
+			assert.NoError(t, err)
+			assert.NotEqual(t, first, second)
+		})
+	}
+}
+
+func TestDerivedTokenSource(t *testing.T) {
+	settings := token.Settings{Format: networkingv1alpha1.TokenFormatBase32, EntropyBits: 128}
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	first, err := token.DerivedSource{Key: key, Message: []byte("default/randomIngress\n1")}.NewToken(settings)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[a-z2-7]{26}$`, first)
+
+	// Deterministic for the same key and message.
+	again, err := token.DerivedSource{Key: key, Message: []byte("default/randomIngress\n1")}.NewToken(settings)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	otherEpoch, err := token.DerivedSource{Key: key, Message: []byte("default/randomIngress\n2")}.NewToken(settings)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, otherEpoch)
+
+	otherKey, err := token.DerivedSource{Key: []byte("fedcba9876543210fedcba9876543210"), Message: []byte("default/randomIngress\n1")}.NewToken(settings)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, otherKey)
+
+	uuid, err := token.DerivedSource{Key: key, Message: []byte("default/randomIngress\n1")}.NewToken(token.Settings{Format: networkingv1alpha1.TokenFormatUUIDv4})
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+}
@@ -0,0 +1,379 @@
+/*
+Copyright 2022 the random-ingress-operator authors.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	networkingv1alpha1 "github.com/BackMarket-oss/random-ingress-operator/api/v1alpha1"
+)
+
+var (
+	// virtualServiceGVK identifies Istio VirtualServices, which are handled as unstructured objects.
+	virtualServiceGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "VirtualService"}
+
+	// istioGatewayGVK identifies the Istio Gateways whose hosts follow the generated VirtualServices.
+	istioGatewayGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "Gateway"}
+)
+
+const (
+	// meshGateway is the reserved gateway name binding VirtualServices to the sidecars of the mesh.
+	meshGateway = "mesh"
+
+	// gatewayHostsFinalizer holds the deletion of RandomIngresses until the hosts they added to a Gateway are removed.
+	gatewayHostsFinalizer = "networking.backmarket.io/gateway-hosts"
+)
+
+// virtualServiceTarget generates Istio VirtualServices from spec.virtualServiceTemplate.
+// Placeholders are substituted in the hosts of the VirtualServices.
+type virtualServiceTarget struct{}
+
+func (virtualServiceTarget) base() unstructuredTarget {
+	return unstructuredTarget{gvk: virtualServiceGVK}
+}
+
+func (t virtualServiceTarget) groupVersionKind() schema.GroupVersionKind {
+	return virtualServiceGVK
+}
+
+func (t virtualServiceTarget) newObject() client.Object {
+	return t.base().newObject()
+}
+
+func (t virtualServiceTarget) list(ctx context.Context, c client.Client, opts ...client.ListOption) ([]client.Object, error) {
+	return t.base().list(ctx, c, opts...)
+}
+
+// template returns the object template of the spec, nil if no VirtualService is generated.
+func (virtualServiceTarget) template(spec *networkingv1alpha1.RandomIngressSpec) *networkingv1alpha1.ObjectTemplateSpec {
+	if spec.VirtualServiceTemplate == nil {
+		return nil
+	}
+
+	return &spec.VirtualServiceTemplate.ObjectTemplateSpec
+}
+
+func (t virtualServiceTarget) templateMetadata(spec *networkingv1alpha1.RandomIngressSpec) networkingv1alpha1.IngressTemplateMetadata {
+	if template := t.template(spec); template != nil {
+		return template.Metadata
+	}
+
+	return networkingv1alpha1.IngressTemplateMetadata{}
+}
+
+func (t virtualServiceTarget) templateHosts(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	serviceSpec, err := decodeTemplateSpec(t.template(spec))
+	if err != nil {
+		return nil
+	}
+
+	hosts, _, _ := unstructured.NestedStringSlice(serviceSpec, "hosts")
+	return hosts
+}
+
+func (t virtualServiceTarget) templateValues(spec *networkingv1alpha1.RandomIngressSpec) []string {
+	return t.templateHosts(spec)
+}
+
+func (t virtualServiceTarget) render(spec *networkingv1alpha1.RandomIngressSpec, meta metav1.ObjectMeta, tokens map[string]ingressToken) (client.Object, error) {
+	serviceSpec, err := decodeTemplateSpec(t.template(spec))
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, _, err := unstructured.NestedStringSlice(serviceSpec, "hosts")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hosts {
+		hosts[i] = replacePlaceholders(hosts[i], tokens)
+	}
+
+	if err := unstructured.SetNestedStringSlice(serviceSpec, hosts, "hosts"); err != nil {
+		return nil, err
+	}
+
+	return t.base().newUnstructured(meta, serviceSpec), nil
+}
+
+// validate checks that the VirtualService lists hosts, which all carry a placeholder,
+// and that it is bound to the Gateway whose hosts are synced.
+func (t virtualServiceTarget) validate(r *RandomIngressReconciler, spec *networkingv1alpha1.RandomIngressSpec) (errs field.ErrorList) {
+	specPath := field.NewPath("spec", "virtualServiceTemplate", "spec")
+	hostsPath := specPath.Child("hosts")
+
+	serviceSpec, err := decodeTemplateSpec(t.template(spec))
+	if err != nil {
+		return append(errs, field.Invalid(specPath, string(spec.VirtualServiceTemplate.Spec.Raw), err.Error()))
+	}
+
+	hosts, found, err := unstructured.NestedStringSlice(serviceSpec, "hosts")
+	switch {
+	case err != nil:
+		return append(errs, field.Invalid(hostsPath, serviceSpec["hosts"], "must be a list of hosts"))
+	case !found || len(hosts) == 0:
+		return append(errs, field.Required(hostsPath, "virtual services must be restricted to random hosts"))
+	}
+
+	for i, host := range hosts {
+		if !placeholderPattern.MatchString(host) {
+			errs = append(errs, field.Invalid(hostsPath.Index(i), host, randomPlaceholderMissingError))
+		}
+	}
+
+	if sync := spec.VirtualServiceTemplate.SyncGateway; sync != nil {
+		gateways, _, _ := unstructured.NestedStringSlice(serviceSpec, "gateways")
+		if !boundToGateway(gateways, sync.Name) {
+			errs = append(errs, field.Invalid(specPath.Child("gateways"), gateways,
+				fmt.Sprintf("must include the synced gateway %s", sync.Name)))
+		}
+	}
+
+	if r.placeholderTokensValid(spec) {
+		errs = append(errs, validateHostList(spec, hosts, hostsPath.Index, errs)...)
+		errs = append(errs, validateLabels(spec, field.NewPath("spec", "virtualServiceTemplate", "metadata", "labels"))...)
+	}
+
+	return errs
+}
+
+// boundToGateway returns true if the gateways of a VirtualService include the named Gateway,
+// with or without its namespace.
+func boundToGateway(gateways []string, name string) bool {
+	for _, gateway := range gateways {
+		if gateway == name || strings.HasSuffix(gateway, "/"+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (virtualServiceTarget) hosts(obj client.Object) []string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	hosts, _, _ := unstructured.NestedStringSlice(u.Object, "spec", "hosts")
+	return hosts
+}
+
+// servedBy returns the gateways of the VirtualService, as namespace/name, once Istio reports its configuration
+// was distributed to them. Istio only reports it when its status is enabled.
+func (virtualServiceTarget) servedBy(obj client.Object) map[string]bool {
+	gateways := map[string]bool{}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return gateways
+	}
+
+	status, _, _ := unstructured.NestedMap(u.Object, "status")
+	if !conditionTrue(status, "Reconciled") {
+		return gateways
+	}
+
+	names, _, _ := unstructured.NestedStringSlice(u.Object, "spec", "gateways")
+	if len(names) == 0 {
+		names = []string{meshGateway}
+	}
+
+	for _, name := range names {
+		if name != meshGateway && !strings.Contains(name, "/") {
+			name = u.GetNamespace() + "/" + name
+		}
+
+		gateways[name] = true
+	}
+
+	return gateways
+}
+
+// gatewaySyncEnabled returns true if the RandomIngress keeps the hosts of a Gateway in sync, or did so.
+func (r *RandomIngressReconciler) gatewaySyncEnabled(randomIngress *networkingv1alpha1.RandomIngress) bool {
+	template := randomIngress.Spec.VirtualServiceTemplate
+
+	return randomIngress.Status.GatewayHosts != nil ||
+		r.EnableVirtualServices && template != nil && template.SyncGateway != nil
+}
+
+// ensureGatewayHostsFinalizer adds the finalizer removing the hosts of the Gateway on deletion,
+// before any host is added to it.
+func (r *RandomIngressReconciler) ensureGatewayHostsFinalizer(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress) error {
+	if !r.gatewaySyncEnabled(randomIngress) || controllerutil.ContainsFinalizer(randomIngress, gatewayHostsFinalizer) {
+		return nil
+	}
+
+	// The update returns the status stored by the API server, which is the one reconciled from.
+	controllerutil.AddFinalizer(randomIngress, gatewayHostsFinalizer)
+	if err := r.Client.Update(ctx, randomIngress); err != nil {
+		return fmt.Errorf("failed to add finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// finalizeGatewayHosts removes the hosts the RandomIngress added to a Gateway, then lets its deletion proceed.
+func (r *RandomIngressReconciler) finalizeGatewayHosts(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress) error {
+	if !controllerutil.ContainsFinalizer(randomIngress, gatewayHostsFinalizer) {
+		return nil
+	}
+
+	if previous := randomIngress.Status.GatewayHosts; previous != nil {
+		if err := r.updateGatewayHosts(ctx, randomIngress.Namespace, previous.Name, nil, previous.Hosts, nil); err != nil {
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(randomIngress, gatewayHostsFinalizer)
+	if err := r.Client.Update(ctx, randomIngress); err != nil {
+		return fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// syncGatewayHosts keeps the hosts of the Gateway designated by the VirtualService template in sync with the hosts
+// of the live VirtualServices, and removes the hosts it added to a Gateway which is no longer synced.
+func (r *RandomIngressReconciler) syncGatewayHosts(ctx context.Context, randomIngress *networkingv1alpha1.RandomIngress, liveIngresses []client.Object) error {
+	status := &randomIngress.Status
+
+	var sync *networkingv1alpha1.GatewaySync
+	if randomIngress.Spec.VirtualServiceTemplate != nil && r.EnableVirtualServices {
+		sync = randomIngress.Spec.VirtualServiceTemplate.SyncGateway
+	}
+
+	if previous := status.GatewayHosts; previous != nil && (sync == nil || previous.Name != sync.Name) {
+		if err := r.updateGatewayHosts(ctx, randomIngress.Namespace, previous.Name, nil, previous.Hosts, nil); err != nil {
+			return err
+		}
+
+		status.GatewayHosts = nil
+	}
+
+	if sync == nil {
+		return nil
+	}
+
+	var hosts []string
+	for _, live := range liveIngresses {
+		if t, ok := objectTarget(live).(virtualServiceTarget); ok {
+			hosts = append(hosts, t.hosts(live)...)
+		}
+	}
+
+	var stale []string
+	if status.GatewayHosts != nil {
+		stale = status.GatewayHosts.Hosts
+	}
+
+	if err := r.updateGatewayHosts(ctx, randomIngress.Namespace, sync.Name, sync.Servers, stale, hosts); err != nil {
+		return err
+	}
+
+	status.GatewayHosts = &networkingv1alpha1.GatewayHosts{Name: sync.Name, Hosts: hosts}
+	return nil
+}
+
+// updateGatewayHosts removes the stale hosts from every server of the Gateway, and adds the given hosts to the named
+// servers, or to every server if none is named. A missing Gateway has no hosts to remove.
+// Istio rejects servers without hosts: servers left without hosts are removed, unless no server would remain.
+func (r *RandomIngressReconciler) updateGatewayHosts(ctx context.Context, namespace, name string, servers, stale, hosts []string) error {
+	gateway := &unstructured.Unstructured{}
+	gateway.SetGroupVersionKind(istioGatewayGVK)
+
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, gateway); err != nil {
+		if apierrors.IsNotFound(err) && len(hosts) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("failed to get Gateway %s: %w", name, err)
+	}
+
+	removed := map[string]bool{}
+	for _, host := range append(stale, hosts...) {
+		removed[host] = true
+	}
+
+	synced := map[string]bool{}
+	for _, server := range servers {
+		synced[server] = true
+	}
+
+	gatewayServers, _, err := unstructured.NestedSlice(gateway.Object, "spec", "servers")
+	if err != nil {
+		return fmt.Errorf("invalid servers in Gateway %s: %w", name, err)
+	}
+
+	changed := false
+	kept := make([]interface{}, 0, len(gatewayServers))
+	for _, s := range gatewayServers {
+		server, ok := s.(map[string]interface{})
+		if !ok {
+			kept = append(kept, s)
+			continue
+		}
+
+		current, _, _ := unstructured.NestedStringSlice(server, "hosts")
+
+		var updated []string
+		for _, host := range current {
+			if !removed[host] {
+				updated = append(updated, host)
+			}
+		}
+
+		if serverName, _, _ := unstructured.NestedString(server, "name"); len(synced) == 0 || synced[serverName] {
+			updated = append(updated, hosts...)
+		}
+
+		if reflect.DeepEqual(current, updated) {
+			kept = append(kept, server)
+			continue
+		}
+		changed = true
+
+		if len(updated) == 0 {
+			continue
+		}
+
+		if err := unstructured.SetNestedStringSlice(server, updated, "hosts"); err != nil {
+			return err
+		}
+		kept = append(kept, server)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if len(kept) == 0 {
+		return fmt.Errorf("removing hosts would leave Gateway %s without servers, it must be deleted or serve other hosts", name)
+	}
+
+	if err := unstructured.SetNestedSlice(gateway.Object, kept, "spec", "servers"); err != nil {
+		return err
+	}
+
+	if err := r.Client.Update(ctx, gateway); err != nil {
+		return fmt.Errorf("failed to update hosts of Gateway %s: %w", name, err)
+	}
+
+	return nil
+}
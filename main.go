@@ -18,6 +18,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
@@ -45,7 +46,20 @@ func main() {
 
 	var ingressMaxLifetime time.Duration
 	var ingressHandoverDuration time.Duration
+	var ingressLifetimeLowerBound time.Duration
+	var ingressLifetimeUpperBound time.Duration
+	var blackoutWindowsFile string
+	var maxRotationDeferral time.Duration
+	var readinessGateMaxExtension time.Duration
+	var minTokenEntropyBits int
+	var minWordsTokenEntropyBits int
 	var resyncPeriod time.Duration
+	var detectHostConflicts bool
+	var tokenHistorySize int
+	var enableHTTPRoutes bool
+	var enableOpenShiftRoutes bool
+	var enableVirtualServices bool
+	var enableTraefikIngressRoutes bool
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -56,8 +70,37 @@ func main() {
 	flag.DurationVar(&ingressHandoverDuration, "ingress-handover-duration", 10*time.Minute,
 		"The duration of handover between an old and a new Ingress. "+
 			"The new ingress will be created that much time before the old one expires")
+	flag.DurationVar(&ingressLifetimeLowerBound, "ingress-lifetime-lower-bound", 15*time.Minute,
+		"The minimum max lifetime a RandomIngress can request. Zero means no lower bound.")
+	flag.DurationVar(&ingressLifetimeUpperBound, "ingress-lifetime-upper-bound", 0,
+		"The maximum max lifetime a RandomIngress can request. Zero means no upper bound.")
+	flag.StringVar(&blackoutWindowsFile, "blackout-windows-file", "",
+		"Path to a YAML file listing blackout windows during which no RandomIngress is rotated.")
+	flag.DurationVar(&maxRotationDeferral, "max-rotation-deferral", 24*time.Hour,
+		"The maximum duration a rotation can be postponed by blackout windows. Zero means no limit.")
+	flag.DurationVar(&readinessGateMaxExtension, "readiness-gate-max-extension", time.Hour,
+		"How long an expired Ingress is kept alive at most, waiting for its replacement to be served, "+
+			"for RandomIngresses with a readiness gate.")
+	flag.IntVar(&minTokenEntropyBits, "min-token-entropy-bits", 122,
+		"The minimum number of random bits of the tokens generated for RandomIngresses.")
+	flag.IntVar(&minWordsTokenEntropyBits, "min-words-token-entropy-bits", 64,
+		"The minimum number of random bits of words tokens, which cannot reach the minimum of other tokens within a DNS label.")
 	flag.DurationVar(&resyncPeriod, "resync-period", 5*time.Minute,
 		"How often the controller must force a refresh of all RandomIngresses.")
+	flag.BoolVar(&detectHostConflicts, "detect-host-conflicts", true,
+		"Look for other Ingresses serving the hosts of RandomIngresses, and generate new tokens on collision.")
+	flag.IntVar(&tokenHistorySize, "token-history-size", 100,
+		"The number of issued tokens remembered by each RandomIngress, as salted hashes, none of which is issued again. "+
+			"Zero disables the token history.")
+	flag.BoolVar(&enableHTTPRoutes, "enable-http-routes", false,
+		"Allow RandomIngresses to generate Gateway API HTTPRoutes. The gateway.networking.k8s.io/v1 CRDs must be installed.")
+	flag.BoolVar(&enableOpenShiftRoutes, "enable-openshift-routes", false,
+		"Allow RandomIngresses to generate OpenShift Routes. The route.openshift.io/v1 API must be served by the cluster.")
+	flag.BoolVar(&enableVirtualServices, "enable-virtual-services", false,
+		"Allow RandomIngresses to generate Istio VirtualServices, and to sync the hosts of Istio Gateways. "+
+			"The networking.istio.io/v1beta1 CRDs must be installed.")
+	flag.BoolVar(&enableTraefikIngressRoutes, "enable-traefik-ingress-routes", false,
+		"Allow RandomIngresses to generate Traefik IngressRoutes. The traefik.io/v1alpha1 CRDs must be installed.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -66,6 +109,15 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var blackoutWindows []networkingv1alpha1.BlackoutWindow
+	if blackoutWindowsFile != "" {
+		var err error
+		if blackoutWindows, err = controllers.LoadBlackoutWindows(blackoutWindowsFile); err != nil {
+			setupLog.Error(err, "unable to load blackout windows", "file", blackoutWindowsFile)
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -74,6 +126,8 @@ func main() {
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "98a65b12.backmarket.io",
 		SyncPeriod:             &resyncPeriod,
+		// Routes of other APIs are handled as unstructured objects, listed through the indexes of the cache.
+		NewClient: cluster.ClientBuilderWithOptions(cluster.ClientOptions{CacheUnstructured: true}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -81,10 +135,23 @@ func main() {
 	}
 
 	if err = (&controllers.RandomIngressReconciler{
-		Client:                  mgr.GetClient(),
-		Scheme:                  mgr.GetScheme(),
-		IngressMaxLifetime:      ingressMaxLifetime,
-		IngressHandoverDuration: ingressHandoverDuration,
+		Client:                     mgr.GetClient(),
+		Scheme:                     mgr.GetScheme(),
+		IngressMaxLifetime:         ingressMaxLifetime,
+		IngressHandoverDuration:    ingressHandoverDuration,
+		IngressLifetimeLowerBound:  ingressLifetimeLowerBound,
+		IngressLifetimeUpperBound:  ingressLifetimeUpperBound,
+		BlackoutWindows:            blackoutWindows,
+		MaxRotationDeferral:        maxRotationDeferral,
+		ReadinessGateMaxExtension:  readinessGateMaxExtension,
+		MinTokenEntropyBits:        minTokenEntropyBits,
+		MinWordsTokenEntropyBits:   minWordsTokenEntropyBits,
+		DetectHostConflicts:        detectHostConflicts,
+		TokenHistorySize:           tokenHistorySize,
+		EnableHTTPRoutes:           enableHTTPRoutes,
+		EnableOpenShiftRoutes:      enableOpenShiftRoutes,
+		EnableVirtualServices:      enableVirtualServices,
+		EnableTraefikIngressRoutes: enableTraefikIngressRoutes,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RandomIngress")
 		os.Exit(1)